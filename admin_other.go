@@ -6,3 +6,7 @@ package main
 func ensureAdmin() error {
 	return nil
 }
+
+func checkAdmin() error {
+	return nil
+}
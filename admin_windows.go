@@ -4,6 +4,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 	"strings"
@@ -27,6 +28,18 @@ func ensureAdmin() error {
 	return nil
 }
 
+// checkAdmin 检查是否以管理员权限运行，不自动提权（用于需要返回退出码的子命令）
+func checkAdmin() error {
+	elevated, err := isWindowsElevated()
+	if err != nil {
+		return err
+	}
+	if !elevated {
+		return errors.New("当前不是管理员权限，请在管理员终端中运行")
+	}
+	return nil
+}
+
 func isWindowsElevated() (bool, error) {
 	var token windows.Token
 	if err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_QUERY, &token); err != nil {
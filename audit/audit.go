@@ -0,0 +1,288 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	EventReceived = "received" // 收到服务器指令
+	EventRejected = "rejected" // 指令被拒绝（签名、策略、参数错误等）
+	EventOutcome  = "outcome"  // 指令执行结果
+)
+
+// 默认轮转参数
+const (
+	DefaultMaxSize    = 10 * 1024 * 1024
+	DefaultMaxBackups = 5
+)
+
+// redactedValue 脱敏后的占位值
+const redactedValue = "***"
+
+// sensitiveKeys 需要脱敏的字段名片段（小写匹配）
+var sensitiveKeys = []string{"token", "secret", "password", "passphrase", "signature", "credential"}
+
+// Entry 审计记录
+type Entry struct {
+	Time       time.Time       `json:"time"`
+	Event      string          `json:"event"`
+	Type       string          `json:"type"`
+	Ref        string          `json:"ref,omitempty"` // 关联的任务 ID / 请求 ID
+	Signed     bool            `json:"signed,omitempty"`
+	Nonce      string          `json:"nonce,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	Code       string          `json:"code,omitempty"`
+	Result     string          `json:"result,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	DurationMs int64           `json:"duration_ms,omitempty"`
+}
+
+// Logger 只追加的审计日志（JSON Lines，按大小轮转）
+type Logger struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+	mu   sync.Mutex
+}
+
+// Open 打开审计日志
+func Open(path string, maxSize int64, maxBackups int) (*Logger, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxBackups < 0 {
+		maxBackups = 0
+	}
+
+	l := &Logger{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := l.openFile(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Path 获取日志文件路径
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// Record 写入一条记录；Logger 为 nil 时忽略
+func (l *Logger) Record(e *Entry) error {
+	if l == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Payload = Redact(e.Payload)
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return errors.New("审计日志已关闭")
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return fmt.Errorf("轮转审计日志失败: %w", err)
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// Close 关闭日志
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// openFile 以追加方式打开日志文件
+func (l *Logger) openFile() error {
+	if dir := filepath.Dir(l.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+// rotate 轮转：audit.log -> audit.log.1 -> audit.log.2 ...，超出数量的备份被删除
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	if l.maxBackups == 0 {
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return l.openFile()
+	}
+
+	os.Remove(backupPath(l.path, l.maxBackups))
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(l.path, i), backupPath(l.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(l.path, backupPath(l.path, 1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return l.openFile()
+}
+
+// backupPath 第 n 个备份文件路径
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// Redact 将 JSON 中敏感字段的值替换为占位符
+func Redact(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+// redactValue 递归脱敏
+func redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if isSensitiveKey(k) {
+				val[k] = redactedValue
+				continue
+			}
+			val[k] = redactValue(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactValue(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// isSensitiveKey 检查字段名是否敏感
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadAll 按时间顺序读取日志及其轮转备份中的所有记录
+func ReadAll(path string) ([]Entry, error) {
+	var files []string
+	for i := 1; ; i++ {
+		p := backupPath(path, i)
+		if _, err := os.Stat(p); err != nil {
+			break
+		}
+		files = append(files, p)
+	}
+	// 备份编号越大越旧
+	for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
+		files[i], files[j] = files[j], files[i]
+	}
+	files = append(files, path)
+
+	var entries []Entry
+	for _, p := range files {
+		fileEntries, err := readFile(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+// readFile 读取单个日志文件，跳过无法解析的行
+func readFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
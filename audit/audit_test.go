@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	raw := json.RawMessage(`{"device_token":"abc","nested":{"Password":"p","keep":"v"},"list":[{"api_secret":"s"}]}`)
+	got := string(Redact(raw))
+
+	for _, secret := range []string{`"abc"`, `"p"`, `"s"`} {
+		if strings.Contains(got, secret) {
+			t.Fatalf("敏感字段未脱敏: %s", got)
+		}
+	}
+	if !strings.Contains(got, `"keep":"v"`) {
+		t.Fatalf("普通字段不应被修改: %s", got)
+	}
+}
+
+func TestRotateAndReadAll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path, 200, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := l.Record(&Entry{Event: EventReceived, Type: "run_task", Ref: string(rune('a' + i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadAll(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 || len(entries) >= 10 {
+		t.Fatalf("轮转后应只保留部分记录, got %d", len(entries))
+	}
+
+	// 记录按时间顺序排列，且最后一条是最新写入的
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Ref <= entries[i-1].Ref {
+			t.Fatalf("记录顺序错误: %q 在 %q 之后", entries[i].Ref, entries[i-1].Ref)
+		}
+	}
+	if last := entries[len(entries)-1].Ref; last != "j" {
+		t.Fatalf("最后一条记录 = %q, want %q", last, "j")
+	}
+}
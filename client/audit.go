@@ -0,0 +1,67 @@
+package client
+
+import (
+	"log"
+
+	"maaend-client/audit"
+)
+
+// SetAuditLog 设置审计日志
+func (c *Client) SetAuditLog(l *audit.Logger) {
+	c.audit = l
+}
+
+// commandRef 提取指令关联的任务 ID / 请求 ID
+func commandRef(msg *Message) string {
+	var ref struct {
+		JobID     string `json:"job_id"`
+		RequestID string `json:"request_id"`
+	}
+	msg.ParsePayload(&ref)
+	if ref.JobID != "" {
+		return ref.JobID
+	}
+	return ref.RequestID
+}
+
+// auditReceived 记录收到的服务器指令
+func (c *Client) auditReceived(msg *Message) {
+	c.recordAudit(&audit.Entry{
+		Event:   audit.EventReceived,
+		Type:    msg.Type,
+		Ref:     commandRef(msg),
+		Signed:  msg.Signature != "",
+		Nonce:   msg.Nonce,
+		Payload: msg.Payload,
+	})
+}
+
+// auditRejected 记录被拒绝的服务器指令
+func (c *Client) auditRejected(msg *Message, code, message string) {
+	c.recordAudit(&audit.Entry{
+		Event:   audit.EventRejected,
+		Type:    msg.Type,
+		Ref:     commandRef(msg),
+		Code:    code,
+		Message: message,
+	})
+}
+
+// auditOutcome 记录服务器指令的执行结果
+func (c *Client) auditOutcome(msgType, ref, result, message string, durationMs int64) {
+	c.recordAudit(&audit.Entry{
+		Event:      audit.EventOutcome,
+		Type:       msgType,
+		Ref:        ref,
+		Result:     result,
+		Message:    message,
+		DurationMs: durationMs,
+	})
+}
+
+// recordAudit 写入审计日志，失败时只输出日志
+func (c *Client) recordAudit(e *audit.Entry) {
+	if err := c.audit.Record(e); err != nil {
+		log.Printf("[Client] 写入审计日志失败: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// 服务器对认证时能力哈希的回复（AuthenticatedPayload.Capabilities）
+const (
+	CapabilitiesUnchanged = "unchanged" // 服务器已有 capabilities_hash 对应的版本，无需上报
+	CapabilitiesDiff      = "diff"      // 服务器已有 capabilities_base_hash 对应的版本，上报差异
+	CapabilitiesFull      = "full"      // 上报完整能力（为空时相同，兼容不支持哈希的服务器）
+)
+
+// capabilitiesCache 服务器已知的设备能力（上次成功上报的版本），用于计算差异
+type capabilitiesCache struct {
+	Hash         string               `json:"hash"`
+	Capabilities *CapabilitiesPayload `json:"capabilities"`
+}
+
+// CapabilitiesHash 计算设备能力的内容哈希：不含 hash 字段的 JSON 序列化结果的 SHA-256
+// 结构体字段按定义顺序、map 按键排序，相同内容的哈希稳定
+func CapabilitiesHash(caps *CapabilitiesPayload) string {
+	clone := *caps
+	clone.Hash = ""
+	data, err := json.Marshal(&clone)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// DiffCapabilities 计算 current 相对于 base 的差异
+// 任务按名称比较，变化的任务整体替换；控制器、资源、选项依赖等较小的字段总是完整给出
+func DiffCapabilities(base, current *CapabilitiesPayload) *CapabilitiesDiffPayload {
+	diff := &CapabilitiesDiffPayload{
+		BaseHash:           base.Hash,
+		Hash:               current.Hash,
+		TaskOrder:          make([]string, 0, len(current.Tasks)),
+		Controllers:        current.Controllers,
+		Resources:          current.Resources,
+		OptionDependencies: current.OptionDependencies,
+		Language:           current.Language,
+		Languages:          current.Languages,
+	}
+
+	baseTasks := make(map[string]TaskInfo, len(base.Tasks))
+	for _, task := range base.Tasks {
+		baseTasks[task.Name] = task
+	}
+	for _, task := range current.Tasks {
+		diff.TaskOrder = append(diff.TaskOrder, task.Name)
+		prev, ok := baseTasks[task.Name]
+		switch {
+		case !ok:
+			diff.AddedTasks = append(diff.AddedTasks, task)
+		case !reflect.DeepEqual(prev, task):
+			diff.ChangedTasks = append(diff.ChangedTasks, task)
+		}
+		delete(baseTasks, task.Name)
+	}
+	for _, task := range base.Tasks {
+		if _, removed := baseTasks[task.Name]; removed {
+			diff.RemovedTasks = append(diff.RemovedTasks, task.Name)
+		}
+	}
+	return diff
+}
+
+// SetCapabilitiesCache 设置设备能力缓存文件（为空时每次认证都上报完整能力）
+func (c *Client) SetCapabilitiesCache(path string) {
+	c.capsCachePath = path
+}
+
+// loadCapabilitiesCache 读取上次成功上报的设备能力，不存在或无法解析时返回 nil
+func (c *Client) loadCapabilitiesCache() *capabilitiesCache {
+	if c.capsCachePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.capsCachePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[Client] 读取能力缓存失败: %v", err)
+		}
+		return nil
+	}
+	var cache capabilitiesCache
+	if err := json.Unmarshal(data, &cache); err != nil || cache.Capabilities == nil || cache.Hash == "" {
+		log.Printf("[Client] 能力缓存无效，将上报完整能力")
+		return nil
+	}
+	return &cache
+}
+
+// saveCapabilitiesCache 保存已上报的设备能力（先写临时文件再替换）
+func (c *Client) saveCapabilitiesCache(caps *CapabilitiesPayload) {
+	if c.capsCachePath == "" {
+		return
+	}
+	data, err := json.Marshal(&capabilitiesCache{Hash: caps.Hash, Capabilities: caps})
+	if err != nil {
+		log.Printf("[Client] 序列化能力缓存失败: %v", err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.capsCachePath), ".capabilities-*.tmp")
+	if err != nil {
+		log.Printf("[Client] 保存能力缓存失败: %v", err)
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil {
+		writeErr = os.Rename(tmp.Name(), c.capsCachePath)
+	}
+	if writeErr != nil {
+		os.Remove(tmp.Name())
+		log.Printf("[Client] 保存能力缓存失败: %v", writeErr)
+	}
+}
+
+// prepareCapabilities 认证前计算当前设备能力及哈希，保存到 pendingCaps 等待服务器回复
+func (c *Client) prepareCapabilities() *CapabilitiesPayload {
+	if c.maaWrapper == nil {
+		return nil
+	}
+	caps, err := c.maaWrapper.GetCapabilities("", false)
+	if err != nil {
+		log.Printf("[Client] 获取设备能力失败: %v", err)
+		return nil
+	}
+	caps.Hash = CapabilitiesHash(caps)
+
+	c.pendingCapsMu.Lock()
+	c.pendingCaps = caps
+	c.pendingCapsMu.Unlock()
+	return caps
+}
+
+// reportCapabilities 按服务器对能力哈希的回复上报设备能力
+func (c *Client) reportCapabilities(mode string) {
+	c.pendingCapsMu.Lock()
+	caps := c.pendingCaps
+	c.pendingCaps = nil
+	c.pendingCapsMu.Unlock()
+
+	if caps == nil {
+		c.SendCapabilities()
+		return
+	}
+
+	switch mode {
+	case CapabilitiesUnchanged:
+		log.Printf("[Client] 设备能力未变化，跳过上报 (%.12s)", caps.Hash)
+		c.saveCapabilitiesCache(caps)
+		return
+	case CapabilitiesDiff:
+		cache := c.loadCapabilitiesCache()
+		if cache == nil {
+			log.Printf("[Client] 没有可用于计算差异的能力缓存，上报完整能力")
+			break
+		}
+		diff := DiffCapabilities(cache.Capabilities, caps)
+		log.Printf("[Client] 上报设备能力差异: 新增 %d, 修改 %d, 删除 %d 个任务",
+			len(diff.AddedTasks), len(diff.ChangedTasks), len(diff.RemovedTasks))
+		if err := c.SendMessage(MsgTypeCapabilitiesDiff, diff); err != nil {
+			log.Printf("[Client] 发送能力差异失败: %v", err)
+			return
+		}
+		c.saveCapabilitiesCache(caps)
+		return
+	}
+
+	if err := c.sendFullCapabilities(caps); err != nil {
+		log.Printf("[Client] 上报设备能力失败: %v", err)
+	}
+}
+
+// sendFullCapabilities 上报完整设备能力并更新缓存
+func (c *Client) sendFullCapabilities(caps *CapabilitiesPayload) error {
+	log.Printf("[Client] 上报设备能力: %d 个任务, %d 个控制器, 语言: %s",
+		len(caps.Tasks), len(caps.Controllers), caps.Language)
+
+	if err := c.SendMessage(MsgTypeCapabilities, caps); err != nil {
+		return err
+	}
+	c.saveCapabilitiesCache(caps)
+	return nil
+}
+
+// RefreshCapabilities 设备能力变化（如 interface.json 重新加载）后重新上报
+// 未认证时不发送，下次认证会携带新的能力哈希
+func (c *Client) RefreshCapabilities() {
+	if !c.isConnected() || c.deviceID == "" {
+		return
+	}
+	c.SendCapabilities()
+}
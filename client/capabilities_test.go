@@ -0,0 +1,90 @@
+package client
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCapabilitiesHashAndDiff(t *testing.T) {
+	base := &CapabilitiesPayload{
+		Controllers: []string{"Win32"},
+		Resources:   []string{"Official"},
+		Tasks: []TaskInfo{
+			{Name: "Daily", Label: "日常"},
+			{Name: "Visit", Label: "访问"},
+			{Name: "Old", Label: "旧任务"},
+		},
+		OptionDependencies: map[string][]string{"A": {"B"}, "C": {"D"}},
+	}
+	base.Hash = CapabilitiesHash(base)
+
+	// 哈希与 hash 字段本身无关，map 顺序不影响结果
+	same := *base
+	same.Hash = "other"
+	same.OptionDependencies = map[string][]string{"C": {"D"}, "A": {"B"}}
+	if CapabilitiesHash(&same) != base.Hash || len(base.Hash) != 64 {
+		t.Fatalf("相同内容的哈希不一致: %s", base.Hash)
+	}
+
+	current := &CapabilitiesPayload{
+		Controllers: []string{"Win32", "ADB"},
+		Resources:   []string{"Official"},
+		Tasks: []TaskInfo{
+			{Name: "New", Label: "新任务"},
+			{Name: "Daily", Label: "日常"},
+			{Name: "Visit", Label: "访问好友"},
+		},
+	}
+	current.Hash = CapabilitiesHash(current)
+	if current.Hash == base.Hash {
+		t.Fatal("内容变化后哈希未变化")
+	}
+
+	diff := DiffCapabilities(base, current)
+	if diff.BaseHash != base.Hash || diff.Hash != current.Hash {
+		t.Errorf("hash = %s -> %s", diff.BaseHash, diff.Hash)
+	}
+	if !reflect.DeepEqual(diff.TaskOrder, []string{"New", "Daily", "Visit"}) {
+		t.Errorf("TaskOrder = %v", diff.TaskOrder)
+	}
+	if len(diff.AddedTasks) != 1 || diff.AddedTasks[0].Name != "New" {
+		t.Errorf("AddedTasks = %+v", diff.AddedTasks)
+	}
+	if len(diff.ChangedTasks) != 1 || diff.ChangedTasks[0].Label != "访问好友" {
+		t.Errorf("ChangedTasks = %+v", diff.ChangedTasks)
+	}
+	if !reflect.DeepEqual(diff.RemovedTasks, []string{"Old"}) {
+		t.Errorf("RemovedTasks = %v", diff.RemovedTasks)
+	}
+	if !reflect.DeepEqual(diff.Controllers, current.Controllers) {
+		t.Errorf("Controllers = %v", diff.Controllers)
+	}
+}
+
+func TestCapabilitiesCache(t *testing.T) {
+	c := &Client{}
+	if c.loadCapabilitiesCache() != nil {
+		t.Fatal("未设置缓存路径时应返回 nil")
+	}
+
+	c.SetCapabilitiesCache(filepath.Join(t.TempDir(), "capabilities.json"))
+	if c.loadCapabilitiesCache() != nil {
+		t.Fatal("缓存文件不存在时应返回 nil")
+	}
+
+	caps := &CapabilitiesPayload{
+		Controllers: []string{"Win32"},
+		Tasks:       []TaskInfo{{Name: "Daily", Options: []OptionInfo{{Name: "Mode", Type: "select"}}}},
+	}
+	caps.Hash = CapabilitiesHash(caps)
+	c.saveCapabilitiesCache(caps)
+
+	cache := c.loadCapabilitiesCache()
+	if cache == nil || cache.Hash != caps.Hash {
+		t.Fatalf("cache = %+v", cache)
+	}
+	if CapabilitiesHash(cache.Capabilities) != caps.Hash {
+		t.Error("从缓存读取的能力哈希与保存前不一致")
+	}
+}
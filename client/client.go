@@ -2,25 +2,35 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"math"
+	"net"
 	"net/http"
+	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/gorilla/websocket"
-
+	"maaend-client/audit"
 	"maaend-client/config"
+	"maaend-client/policy"
 )
 
-// Client WebSocket 客户端
+// Client 服务器连接客户端
 type Client struct {
 	config *config.Config
-	conn   *websocket.Conn
+	conn   Transport
+
+	deviceID      string
+	deviceToken   string
+	signingSecret string      // 指令签名密钥（注册或令牌轮换时下发，从不发送给服务器）
+	registering   atomic.Bool // 已发送 register，等待 registered
 
-	deviceID    string
-	deviceToken string
+	// 本地发起解绑时等待服务器确认
+	unbindAck   chan DeviceUnboundPayload
+	unbindAckMu sync.Mutex
 
 	// 当前任务
 	currentJob   *Job
@@ -34,9 +44,21 @@ type Client struct {
 	connected   bool
 	connectedMu sync.RWMutex
 
+	// 服务器指令签名校验
+	verifier *commandVerifier
+
+	// 服务器地址池及当前使用的地址
+	endpoints *endpointPool
+	activeURL string
+
 	// 重连计数
 	reconnectCount int
 
+	// 服务器建议的下次重连延迟（来自 Retry-After 或 going_away）
+	serverRetryDelay time.Duration
+	// 服务器指定的备用地址（仅用于下一次连接）
+	redirectURL string
+
 	// 回调
 	onConnected    func()
 	onDisconnected func()
@@ -44,6 +66,33 @@ type Client struct {
 
 	// MaaWrapper 接口（外部注入）
 	maaWrapper MaaWrapperInterface
+
+	// 本地凭证存储（外部注入）
+	credentials CredentialStore
+
+	// 本地指令权限策略（为空时不限制）
+	policy *policy.Policy
+
+	// 审计日志（为空时不记录）
+	audit *audit.Logger
+
+	// 任务事件回调（本地 API 订阅）
+	onJobEvent func(*Message)
+
+	// 设备能力缓存文件（服务器已知的版本，为空时不缓存）
+	capsCachePath string
+	// 认证时计算的设备能力，等待服务器回复后上报
+	pendingCaps   *CapabilitiesPayload
+	pendingCapsMu sync.Mutex
+}
+
+// CredentialStore 本地凭证存储接口（设备令牌和指令签名密钥的唯一来源）
+type CredentialStore interface {
+	GetDeviceToken() string
+	GetSigningSecret() string
+	SaveCredentials(deviceID, deviceToken, signingSecret, deviceName string) error
+	RotateCredentials(deviceToken, signingSecret string) error
+	ClearCredentials() error
 }
 
 // Job 任务信息
@@ -54,24 +103,35 @@ type Job struct {
 	Tasks      []RunTaskItem
 	StartTime  time.Time
 	Status     string
+	Origin     string // 任务来源: remote（服务器下发）, local（本地 API）
+
+	StrictOptions *bool // 覆盖 maaend.strict_options，为空使用本地配置
 }
 
 // MaaWrapperInterface MaaFramework 封装接口
 type MaaWrapperInterface interface {
-	GetCapabilities() (*CapabilitiesPayload, error)
+	GetCapabilities(lang string, all bool) (*CapabilitiesPayload, error) // lang 为空使用本地配置
 	RunTask(job *Job, statusCh chan<- TaskStatusPayload, logCh chan<- TaskLogPayload) error
 	StopTask() error
 	TakeScreenshot() ([]byte, int, int, error)
 	ClearEventChannels() // 清除事件通道引用，防止关闭后写入导致 panic
 	GetVersion() string  // 获取 MaaEnd 版本
+	// ExplainOptions 解析任务选项并返回追踪记录（不执行任务）
+	ExplainOptions(task string, options map[string]interface{}) (*OptionExplanation, error)
+	// ReloadInterface 重新加载 interface.json（任务执行中时在任务结束后进行）
+	ReloadInterface() (*InterfaceReloadResult, error)
+	// ReloadResource 重新加载资源，name 为空时为当前资源（任务执行中时在下次任务前进行）
+	ReloadResource(name string) (*ResourceReloadResult, error)
 }
 
 // NewClient 创建客户端
 func NewClient(cfg *config.Config) *Client {
 	return &Client{
-		config: cfg,
-		sendCh: make(chan []byte, 256),
-		stopCh: make(chan struct{}),
+		config:    cfg,
+		endpoints: newEndpointPool(cfg.Server.Endpoints(), cfg.Server.URLStrategy),
+		verifier:  newCommandVerifier(cfg.Security.CommandSigning, cfg.Security.SignatureWindow),
+		sendCh:    make(chan []byte, 256),
+		stopCh:    make(chan struct{}),
 	}
 }
 
@@ -80,6 +140,18 @@ func (c *Client) SetMaaWrapper(wrapper MaaWrapperInterface) {
 	c.maaWrapper = wrapper
 }
 
+// SetCredentialStore 设置本地凭证存储，并加载已保存的令牌
+func (c *Client) SetCredentialStore(store CredentialStore) {
+	c.credentials = store
+	c.deviceToken = store.GetDeviceToken()
+	c.signingSecret = store.GetSigningSecret()
+}
+
+// SetPolicy 设置本地指令权限策略
+func (c *Client) SetPolicy(p *policy.Policy) {
+	c.policy = p
+}
+
 // SetCallbacks 设置回调
 func (c *Client) SetCallbacks(onConnected, onDisconnected func(), onMessage func(*Message)) {
 	c.onConnected = onConnected
@@ -89,9 +161,6 @@ func (c *Client) SetCallbacks(onConnected, onDisconnected func(), onMessage func
 
 // Run 运行客户端（阻塞）
 func (c *Client) Run(ctx context.Context) error {
-	// 加载已保存的 token
-	c.deviceToken = c.config.Device.Token
-
 	for {
 		select {
 		case <-ctx.Done():
@@ -135,27 +204,107 @@ func (c *Client) Run(ctx context.Context) error {
 	}
 }
 
-// connect 建立 WebSocket 连接
+// connect 建立与服务器的连接
 func (c *Client) connect() error {
-	dialer := websocket.Dialer{
-		HandshakeTimeout: c.config.Server.ConnectTimeout,
+	tlsConfig, err := buildTLSConfig(c.config.Server.TLS)
+	if err != nil {
+		return fmt.Errorf("TLS 配置错误: %w", err)
 	}
 
 	header := http.Header{}
 	header.Set("User-Agent", "MaaEnd-Client/1.0")
 
-	conn, _, err := dialer.Dial(c.config.Server.WsURL, header)
+	var serverURL, endpointDesc string
+	if c.redirectURL != "" {
+		serverURL = c.redirectURL
+		endpointDesc = "服务器指定的备用地址"
+		c.redirectURL = ""
+	} else {
+		var idx int
+		serverURL, idx = c.endpoints.next()
+		endpointDesc = fmt.Sprintf("地址 %d/%d", idx+1, c.endpoints.size())
+	}
+	if serverURL == "" {
+		return fmt.Errorf("未配置服务器地址")
+	}
+
+	kind, dialURL, err := resolveTransport(c.config.Server.Transport, serverURL)
 	if err != nil {
-		return fmt.Errorf("WebSocket 连接失败: %w", err)
+		c.endpoints.markFailure(serverURL, err)
+		return err
+	}
+
+	log.Printf("[Client] 正在连接 %s (%s, 传输方式: %s)", dialURL, endpointDesc, kind)
+
+	dial, err := c.proxyDialer(dialURL)
+	if err != nil {
+		c.endpoints.markFailure(serverURL, err)
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if c.config.Server.ConnectTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), c.config.Server.ConnectTimeout)
+	}
+	defer cancel()
+
+	conn, err := dialTransport(ctx, kind, transportOptions{
+		URL:         dialURL,
+		Header:      header,
+		Timeout:     c.config.Server.ConnectTimeout,
+		TLSConfig:   tlsConfig,
+		DialContext: dial,
+	})
+	if err != nil {
+		c.endpoints.markFailure(serverURL, err)
+		var pinErr *PinMismatchError
+		if errors.As(err, &pinErr) {
+			return fmt.Errorf("证书指纹校验失败，拒绝连接: %w", err)
+		}
+		var hsErr *HandshakeError
+		if errors.As(err, &hsErr) {
+			if isRetryAfterStatus(hsErr.StatusCode) {
+				if delay, ok := parseRetryAfter(hsErr.Header.Get("Retry-After"), time.Now()); ok {
+					c.serverRetryDelay = delay
+				}
+			}
+		}
+		return fmt.Errorf("连接服务器失败: %w", err)
 	}
 
+	c.endpoints.markSuccess(serverURL)
+	c.activeURL = serverURL
 	c.conn = conn
 	c.setConnected(true)
 
-	log.Printf("[Client] 已连接到服务器: %s", c.config.Server.WsURL)
+	log.Printf("[Client] 已连接到服务器: %s (%s, 传输方式: %s)", dialURL, endpointDesc, kind)
 	return nil
 }
 
+// proxyDialer 获取连接 serverURL 使用的代理拨号函数并记录实际使用的代理，直连时返回 nil
+func (c *Client) proxyDialer(serverURL string) (dialContextFunc, error) {
+	target, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("服务器地址无效: %w", err)
+	}
+
+	proxyURL, source, err := resolveProxy(c.config.Server.Proxy, target)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		log.Printf("[Client] 代理: 直连")
+		return nil, nil
+	}
+
+	dial, err := newProxyDialer(proxyURL, &net.Dialer{Timeout: c.config.Server.ConnectTimeout})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Client] 代理: %s (来源: %s)", proxyURL.Redacted(), source)
+	return dial, nil
+}
+
 // runLoop 主循环
 func (c *Client) runLoop(ctx context.Context) {
 	// 启动写协程
@@ -184,9 +333,9 @@ func (c *Client) readLoop(ctx context.Context) {
 		default:
 		}
 
-		_, message, err := c.conn.ReadMessage()
+		message, err := c.conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
 				log.Printf("[Client] 读消息错误: %v", err)
 			}
 			return
@@ -214,7 +363,7 @@ func (c *Client) writeLoop(ctx context.Context) {
 			if !ok {
 				return
 			}
-			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			if err := c.conn.WriteMessage(message); err != nil {
 				log.Printf("[Client] 写消息失败: %v", err)
 				return
 			}
@@ -244,15 +393,19 @@ func (c *Client) heartbeatLoop(ctx context.Context) {
 func (c *Client) waitForReconnect(ctx context.Context) {
 	c.reconnectCount++
 
-	// 指数退避
-	delay := time.Duration(math.Pow(2, float64(c.reconnectCount-1))) * time.Second
-	maxDelay := c.config.Server.ReconnectMaxDelay
-	if delay > maxDelay {
-		delay = maxDelay
+	minDelay := c.config.Server.ReconnectMinDelay
+	var delay time.Duration
+	if c.serverRetryDelay > 0 {
+		// 服务器指定了重连时间，优先遵循
+		delay = serverDirectedDelay(c.serverRetryDelay, minDelay)
+		c.serverRetryDelay = 0
+		log.Printf("[Client] 按服务器要求 %s 后重连 (第 %d 次)", delay.Round(time.Millisecond), c.reconnectCount)
+	} else {
+		// 带抖动的指数退避
+		delay = backoffDelay(c.reconnectCount, minDelay, c.config.Server.ReconnectMaxDelay)
+		log.Printf("[Client] %s 后重连 (第 %d 次)", delay.Round(time.Millisecond), c.reconnectCount)
 	}
 
-	log.Printf("[Client] %s 后重连 (第 %d 次)", delay, c.reconnectCount)
-
 	select {
 	case <-ctx.Done():
 	case <-time.After(delay):
@@ -312,11 +465,19 @@ func (c *Client) sendAuth() {
 
 	log.Printf("[Client] 版本信息: MaaEnd=%s, Client=%s", maaEndVersion, clientVersion)
 
-	c.SendMessage(MsgTypeAuth, &AuthPayload{
+	auth := &AuthPayload{
 		DeviceToken:   c.deviceToken,
 		MaaEndVersion: maaEndVersion,
 		ClientVersion: clientVersion,
-	})
+	}
+	// 附带能力哈希，服务器已有相同版本时可跳过上报
+	if caps := c.prepareCapabilities(); caps != nil {
+		auth.CapabilitiesHash = caps.Hash
+		if cache := c.loadCapabilitiesCache(); cache != nil && cache.Hash != caps.Hash {
+			auth.CapabilitiesBaseHash = cache.Hash
+		}
+	}
+	c.SendMessage(MsgTypeAuth, auth)
 }
 
 // SendRegister 发送注册消息
@@ -337,6 +498,7 @@ func (c *Client) SendRegister(bindCode string) {
 
 	log.Printf("[Client] 版本信息: MaaEnd=%s, Client=%s", maaEndVersion, clientVersion)
 
+	c.registering.Store(true)
 	c.SendMessage(MsgTypeRegister, &RegisterPayload{
 		BindCode:      bindCode,
 		DeviceName:    c.config.Device.Name,
@@ -347,23 +509,33 @@ func (c *Client) SendRegister(bindCode string) {
 	})
 }
 
-// SendCapabilities 发送设备能力
+// SendCapabilities 发送设备能力（使用本地配置的语言）
 func (c *Client) SendCapabilities() {
+	if err := c.sendCapabilities("", false); err != nil {
+		log.Printf("[Client] %v", err)
+	}
+}
+
+// sendCapabilities 按指定语言发送设备能力，all 为 true 时附带所有语言
+func (c *Client) sendCapabilities(lang string, all bool) error {
 	if c.maaWrapper == nil {
-		log.Printf("[Client] MaaWrapper 未初始化，跳过能力上报")
-		return
+		return fmt.Errorf("MaaWrapper 未初始化，跳过能力上报")
 	}
 
-	capabilities, err := c.maaWrapper.GetCapabilities()
+	capabilities, err := c.maaWrapper.GetCapabilities(lang, all)
 	if err != nil {
-		log.Printf("[Client] 获取设备能力失败: %v", err)
-		return
+		return fmt.Errorf("获取设备能力失败: %w", err)
 	}
+	capabilities.Hash = CapabilitiesHash(capabilities)
 
-	log.Printf("[Client] 上报设备能力: %d 个任务, %d 个控制器",
-		len(capabilities.Tasks), len(capabilities.Controllers))
+	// 只有默认语言的能力作为服务器已知版本缓存
+	if lang == "" && !all {
+		return c.sendFullCapabilities(capabilities)
+	}
 
-	c.SendMessage(MsgTypeCapabilities, capabilities)
+	log.Printf("[Client] 上报设备能力: %d 个任务, %d 个控制器, 语言: %s",
+		len(capabilities.Tasks), len(capabilities.Controllers), capabilities.Language)
+	return c.SendMessage(MsgTypeCapabilities, capabilities)
 }
 
 // sendPing 发送心跳
@@ -386,6 +558,68 @@ func (c *Client) SendTaskCompleted(payload *TaskCompletedPayload) {
 	c.SendMessage(MsgTypeTaskCompleted, payload)
 }
 
+// unbindAckTimeout 等待服务器确认解绑的时间
+var unbindAckTimeout = 15 * time.Second
+
+// RequestUnbind 本地发起解绑：通知服务器，收到 device_unbound 确认后清除本地凭证
+// 发送失败、连接断开或超时未确认时返回错误并保留本地凭证，避免服务器仍认为设备已绑定
+func (c *Client) RequestUnbind(reason string) error {
+	if c.deviceToken == "" {
+		return fmt.Errorf("设备未绑定")
+	}
+
+	ack := make(chan DeviceUnboundPayload, 1)
+	c.unbindAckMu.Lock()
+	c.unbindAck = ack
+	c.unbindAckMu.Unlock()
+	defer func() {
+		c.unbindAckMu.Lock()
+		c.unbindAck = nil
+		c.unbindAckMu.Unlock()
+	}()
+
+	log.Printf("[Client] 发送解绑请求...")
+	if err := c.SendMessage(MsgTypeUnbindDevice, &UnbindDevicePayload{
+		DeviceToken: c.deviceToken,
+		Reason:      reason,
+	}); err != nil {
+		return err
+	}
+
+	select {
+	case payload := <-ack:
+		if !payload.Success {
+			return fmt.Errorf("服务器拒绝解绑: %s", payload.Error)
+		}
+	case <-time.After(unbindAckTimeout):
+		return fmt.Errorf("等待服务器确认解绑超时，本地凭证未清除")
+	case <-c.stopCh:
+		return fmt.Errorf("客户端已停止，本地凭证未清除")
+	}
+
+	return c.clearCredentials()
+}
+
+// SendCommandRejected 通知服务器指令被拒绝（同时写入审计日志）
+func (c *Client) SendCommandRejected(msg *Message, code, message string) {
+	c.auditRejected(msg, code, message)
+
+	// 尽量带上任务 ID / 请求 ID，方便服务器关联
+	var ref struct {
+		JobID     string `json:"job_id"`
+		RequestID string `json:"request_id"`
+	}
+	msg.ParsePayload(&ref)
+
+	c.SendMessage(MsgTypeCommandRejected, &CommandRejectedPayload{
+		Type:      msg.Type,
+		Code:      code,
+		Message:   message,
+		JobID:     ref.JobID,
+		RequestID: ref.RequestID,
+	})
+}
+
 // SendScreenshot 发送截图
 func (c *Client) SendScreenshot(requestID, base64Image string, width, height int, errMsg string) {
 	c.SendMessage(MsgTypeScreenshot, &ScreenshotPayload{
@@ -418,11 +652,49 @@ func (c *Client) IsConnected() bool {
 	return c.isConnected()
 }
 
+// GetActiveURL 获取当前（或最近一次）连接的服务器地址
+func (c *Client) GetActiveURL() string {
+	return c.activeURL
+}
+
+// GetEndpointHealth 获取所有服务器地址的健康状态
+func (c *Client) GetEndpointHealth() []EndpointHealth {
+	return c.endpoints.snapshot()
+}
+
 // GetDeviceID 获取设备ID
 func (c *Client) GetDeviceID() string {
 	return c.deviceID
 }
 
+// saveCredentials 保存注册得到的凭证
+func (c *Client) saveCredentials(deviceID, deviceToken, signingSecret string) error {
+	if c.credentials == nil {
+		return nil
+	}
+	return c.credentials.SaveCredentials(deviceID, deviceToken, signingSecret, c.config.Device.Name)
+}
+
+// replaceToken 替换已保存的令牌，signingSecret 非空时同时替换指令签名密钥
+func (c *Client) replaceToken(newToken, signingSecret string) error {
+	if c.credentials == nil {
+		return nil
+	}
+	return c.credentials.RotateCredentials(newToken, signingSecret)
+}
+
+// clearCredentials 清除内存和本地保存的凭证
+func (c *Client) clearCredentials() error {
+	c.deviceID = ""
+	c.deviceToken = ""
+	c.signingSecret = ""
+
+	if c.credentials == nil {
+		return nil
+	}
+	return c.credentials.ClearCredentials()
+}
+
 // HasToken 检查是否有已保存的 token
 func (c *Client) HasToken() bool {
 	return c.deviceToken != ""
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 多地址选择策略
+const (
+	URLStrategyFailover   = "failover"    // 按配置顺序故障转移，主地址恢复后切回
+	URLStrategyRoundRobin = "round_robin" // 轮询
+	URLStrategySticky     = "sticky"      // 优先上次成功的地址
+)
+
+// endpointCooldown 连接失败的地址在此时间内不会被优先选择
+const endpointCooldown = 30 * time.Second
+
+// EndpointHealth 服务器地址健康状态
+type EndpointHealth struct {
+	URL         string
+	Failures    int // 连续失败次数
+	LastFailure time.Time
+	LastSuccess time.Time
+	LastError   string
+}
+
+// available 检查地址当前是否可优先选择
+func (e *EndpointHealth) available(now time.Time) bool {
+	return e.Failures == 0 || now.Sub(e.LastFailure) >= endpointCooldown
+}
+
+// endpointPool 服务器地址池
+type endpointPool struct {
+	strategy  string
+	endpoints []*EndpointHealth
+	cursor    int // round_robin 下一个位置
+	lastGood  int // sticky 上次成功的位置，-1 表示无
+	mu        sync.Mutex
+}
+
+// newEndpointPool 创建地址池
+func newEndpointPool(urls []string, strategy string) *endpointPool {
+	switch strategy {
+	case URLStrategyFailover, URLStrategyRoundRobin, URLStrategySticky:
+	default:
+		strategy = URLStrategyFailover
+	}
+
+	p := &endpointPool{
+		strategy: strategy,
+		lastGood: -1,
+	}
+	for _, u := range urls {
+		p.endpoints = append(p.endpoints, &EndpointHealth{URL: u})
+	}
+	return p
+}
+
+// next 按策略选择下一个要连接的地址，返回地址和其在列表中的位置
+func (p *endpointPool) next() (string, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.endpoints) == 0 {
+		return "", -1
+	}
+
+	now := time.Now()
+	var idx int
+	switch p.strategy {
+	case URLStrategyRoundRobin:
+		idx = p.firstAvailable(p.cursor, now)
+		p.cursor = (idx + 1) % len(p.endpoints)
+	case URLStrategySticky:
+		start := 0
+		if p.lastGood >= 0 {
+			start = p.lastGood
+		}
+		idx = p.firstAvailable(start, now)
+	default:
+		idx = p.firstAvailable(0, now)
+	}
+
+	return p.endpoints[idx].URL, idx
+}
+
+// firstAvailable 从 start 开始循环查找第一个可用地址；都在冷却中时选择最早失败的地址
+func (p *endpointPool) firstAvailable(start int, now time.Time) int {
+	n := len(p.endpoints)
+	for i := 0; i < n; i++ {
+		idx := (start + i) % n
+		if p.endpoints[idx].available(now) {
+			return idx
+		}
+	}
+
+	oldest := start % n
+	for i := range p.endpoints {
+		if p.endpoints[i].LastFailure.Before(p.endpoints[oldest].LastFailure) {
+			oldest = i
+		}
+	}
+	return oldest
+}
+
+// markSuccess 记录连接成功
+func (p *endpointPool) markSuccess(url string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, e := range p.endpoints {
+		if e.URL == url {
+			e.Failures = 0
+			e.LastSuccess = time.Now()
+			e.LastError = ""
+			p.lastGood = i
+			return
+		}
+	}
+}
+
+// markFailure 记录连接失败
+func (p *endpointPool) markFailure(url string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.endpoints {
+		if e.URL == url {
+			e.Failures++
+			e.LastFailure = time.Now()
+			if err != nil {
+				e.LastError = err.Error()
+			}
+			return
+		}
+	}
+}
+
+// contains 地址是否在配置的地址列表中
+func (p *endpointPool) contains(url string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.endpoints {
+		if e.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectHostAllowed 备用地址是否使用 TLS 且主机在允许列表中（支持 *.example.com 匹配子域名）
+func redirectHostAllowed(rawURL string, hosts []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "wss" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return false
+	}
+	for _, allowed := range hosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if allowed != "" && host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// size 地址数量
+func (p *endpointPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.endpoints)
+}
+
+// snapshot 获取所有地址健康状态的副本
+func (p *endpointPool) snapshot() []EndpointHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make([]EndpointHealth, len(p.endpoints))
+	for i, e := range p.endpoints {
+		result[i] = *e
+	}
+	return result
+}
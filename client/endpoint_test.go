@@ -0,0 +1,52 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEndpointPoolFailover(t *testing.T) {
+	p := newEndpointPool([]string{"ws://primary", "ws://backup"}, URLStrategyFailover)
+
+	if url, _ := p.next(); url != "ws://primary" {
+		t.Fatalf("首次应选择主地址, got %s", url)
+	}
+	p.markFailure("ws://primary", errors.New("refused"))
+
+	if url, _ := p.next(); url != "ws://backup" {
+		t.Fatalf("主地址失败后应切换到备用地址, got %s", url)
+	}
+	p.markSuccess("ws://backup")
+
+	if url, _ := p.next(); url != "ws://backup" {
+		t.Fatalf("主地址冷却期内应继续使用备用地址, got %s", url)
+	}
+}
+
+func TestEndpointPoolRoundRobin(t *testing.T) {
+	p := newEndpointPool([]string{"ws://a", "ws://b", "ws://c"}, URLStrategyRoundRobin)
+
+	want := []string{"ws://a", "ws://b", "ws://c", "ws://a"}
+	for i, w := range want {
+		if url, _ := p.next(); url != w {
+			t.Fatalf("第 %d 次选择 %s, want %s", i+1, url, w)
+		}
+	}
+}
+
+func TestEndpointPoolSticky(t *testing.T) {
+	p := newEndpointPool([]string{"ws://a", "ws://b"}, URLStrategySticky)
+
+	p.markFailure("ws://a", errors.New("refused"))
+	if url, _ := p.next(); url != "ws://b" {
+		t.Fatalf("got %s, want ws://b", url)
+	}
+	p.markSuccess("ws://b")
+
+	// 即使 a 恢复可用，sticky 仍优先上次成功的地址
+	p.markSuccess("ws://a")
+	p.markSuccess("ws://b")
+	if url, _ := p.next(); url != "ws://b" {
+		t.Fatalf("got %s, want ws://b", url)
+	}
+}
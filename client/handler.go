@@ -2,14 +2,35 @@ package client
 
 import (
 	"encoding/base64"
+	"errors"
 	"log"
+	"strings"
 	"time"
-
-	"maaend-client/config"
 )
 
 // handleMessage 处理服务端消息
 func (c *Client) handleMessage(msg *Message) {
+	// 记录服务器指令
+	if signedCommandTypes[msg.Type] {
+		c.auditReceived(msg)
+	}
+
+	// 校验指令签名
+	if rejection := c.verifier.verify(msg, deriveSigningKey(c.signingSecret), time.Now()); rejection != nil {
+		log.Printf("[Client] 拒绝服务器指令 %s: %s", msg.Type, rejection.Message)
+		c.SendCommandRejected(msg, rejection.Code, rejection.Message)
+		return
+	}
+
+	// 检查本地策略是否允许该指令
+	if signedCommandTypes[msg.Type] {
+		if violation := c.policy.CheckMessage(msg.Type); violation != nil {
+			log.Printf("[Client] 拒绝服务器指令 %s: %s", msg.Type, violation.Message)
+			c.SendCommandRejected(msg, violation.Code, violation.Message)
+			return
+		}
+	}
+
 	switch msg.Type {
 	case MsgTypeRegistered:
 		c.handleRegistered(msg)
@@ -27,6 +48,22 @@ func (c *Client) handleMessage(msg *Message) {
 		c.handleRequestScreenshot(msg)
 	case MsgTypeError:
 		c.handleError(msg)
+	case MsgTypeGoingAway:
+		c.handleGoingAway(msg)
+	case MsgTypeRotateToken:
+		c.handleRotateToken(msg)
+	case MsgTypeUnbind:
+		c.handleUnbind(msg)
+	case MsgTypeDeviceUnbound:
+		c.handleDeviceUnbound(msg)
+	case MsgTypeExplainOptions:
+		c.handleExplainOptions(msg)
+	case MsgTypeRequestCapabilities:
+		c.handleRequestCapabilities(msg)
+	case MsgTypeReloadInterface:
+		c.handleReloadInterface(msg)
+	case MsgTypeReloadResource:
+		c.handleReloadResource(msg)
 	default:
 		log.Printf("[Client] 未知消息类型: %s", msg.Type)
 	}
@@ -45,13 +82,28 @@ func (c *Client) handleRegistered(msg *Message) {
 		return
 	}
 
+	// 注册响应会覆盖本地凭证：只接受发送 register 之后的第一个响应；
+	// 签名策略为 required 时不允许覆盖已有凭证（该消息无法签名，需先解绑）
+	if !c.registering.CompareAndSwap(true, false) {
+		log.Printf("[Client] 忽略未请求的注册响应")
+		return
+	}
+	if c.verifier.mode == SigningRequired && c.HasToken() {
+		log.Printf("[Client] 签名策略为 required，拒绝用注册响应覆盖已有凭证")
+		return
+	}
+	if payload.SigningSecret == "" {
+		log.Printf("[Client] 警告: 注册响应未包含指令签名密钥，无法校验服务器指令签名")
+	}
+
 	c.deviceID = payload.DeviceID
 	c.deviceToken = payload.DeviceToken
+	c.signingSecret = payload.SigningSecret
 
 	log.Printf("[Client] 注册成功！设备ID: %s", payload.DeviceID)
 
-	// 保存 token 到配置文件
-	if err := config.SaveToken(payload.DeviceToken); err != nil {
+	// 保存凭证
+	if err := c.saveCredentials(payload.DeviceID, payload.DeviceToken, payload.SigningSecret); err != nil {
 		log.Printf("[Client] 保存设备令牌失败: %v", err)
 	} else {
 		log.Printf("[Client] 设备令牌已保存")
@@ -73,8 +125,8 @@ func (c *Client) handleAuthenticated(msg *Message) {
 
 	log.Printf("[Client] 认证成功！设备ID: %s, 用户: %s", payload.DeviceID, payload.UserNickname)
 
-	// 上报设备能力
-	c.SendCapabilities()
+	// 按服务器对能力哈希的回复上报设备能力（完整、差异或跳过）
+	c.reportCapabilities(payload.Capabilities)
 }
 
 // handleAuthFailed 处理认证失败
@@ -88,8 +140,9 @@ func (c *Client) handleAuthFailed(msg *Message) {
 	log.Printf("[Client] 认证失败: %s - %s", payload.Error, payload.Message)
 
 	// 清除本地 token
-	c.deviceToken = ""
-	config.SaveToken("")
+	if err := c.clearCredentials(); err != nil {
+		log.Printf("[Client] 清除本地凭证失败: %v", err)
+	}
 
 	log.Printf("[Client] 已清除本地令牌，请重新绑定设备")
 }
@@ -104,58 +157,44 @@ func (c *Client) handleRunTask(msg *Message) {
 	var payload RunTaskPayload
 	if err := msg.ParsePayload(&payload); err != nil {
 		log.Printf("[Client] 解析任务请求失败: %v", err)
+		c.auditRejected(msg, "invalid_payload", err.Error())
 		return
 	}
 
 	log.Printf("[Client] 收到任务: %s, 控制器: %s, 资源: %s, 任务数: %d",
 		payload.JobID, payload.Controller, payload.Resource, len(payload.Tasks))
 
-	// 检查是否有正在执行的任务
-	if c.GetCurrentJob() != nil {
-		log.Printf("[Client] 已有任务正在执行，拒绝新任务")
-		c.SendTaskCompleted(&TaskCompletedPayload{
-			JobID:      payload.JobID,
-			Status:     "failed",
-			Error:      "设备忙碌",
-			DurationMs: 0,
-		})
-		return
+	// 检查本地策略
+	taskNames := make([]string, 0, len(payload.Tasks))
+	for _, task := range payload.Tasks {
+		taskNames = append(taskNames, task.Name)
 	}
-
-	// 检查 MaaWrapper
-	if c.maaWrapper == nil {
-		log.Printf("[Client] MaaWrapper 未初始化")
-		c.SendTaskCompleted(&TaskCompletedPayload{
-			JobID:      payload.JobID,
-			Status:     "failed",
-			Error:      "MaaFramework 未初始化",
-			DurationMs: 0,
-		})
+	if violation := c.policy.CheckRun(payload.Controller, payload.Resource, taskNames, time.Now()); violation != nil {
+		log.Printf("[Client] 本地策略拒绝任务 %s: %s", payload.JobID, violation.Message)
+		c.SendCommandRejected(msg, violation.Code, violation.Message)
 		return
 	}
 
-	// 创建任务
+	// 创建并启动任务（与本地 API 共用同一个任务槽位）
 	job := &Job{
 		JobID:      payload.JobID,
 		Controller: payload.Controller,
 		Resource:   payload.Resource,
 		Tasks:      payload.Tasks,
-		StartTime:  time.Now(),
-		Status:     "running",
-	}
-	c.SetCurrentJob(job)
-
-	// 发送任务开始状态
-	c.SendTaskStatus(&TaskStatusPayload{
-		JobID:       payload.JobID,
-		Status:      "running",
-		CurrentTask: "",
-		Progress:    JobProgress{Completed: 0, Total: len(payload.Tasks)},
-		Message:     "任务开始执行",
-	})
+		Origin:     JobOriginRemote,
 
-	// 异步执行任务
-	go c.executeTask(job)
+		StrictOptions: payload.StrictOptions,
+	}
+	if err := c.StartJob(job); err != nil {
+		log.Printf("[Client] 无法执行任务: %v", err)
+		c.auditOutcome(msg.Type, payload.JobID, "failed", err.Error(), 0)
+		c.SendTaskCompleted(&TaskCompletedPayload{
+			JobID:      payload.JobID,
+			Status:     "failed",
+			Error:      err.Error(),
+			DurationMs: 0,
+		})
+	}
 }
 
 // executeTask 执行任务
@@ -169,14 +208,14 @@ func (c *Client) executeTask(job *Job) {
 	// 启动状态转发协程
 	go func() {
 		for status := range statusCh {
-			c.SendTaskStatus(&status)
+			c.emitJobEvent(job, MsgTypeTaskStatus, &status)
 		}
 	}()
 
 	// 启动日志转发协程
 	go func() {
 		for logEntry := range logCh {
-			c.SendTaskLog(&logEntry)
+			c.emitJobEvent(job, MsgTypeTaskLog, &logEntry)
 		}
 	}()
 
@@ -199,15 +238,23 @@ func (c *Client) executeTask(job *Job) {
 	// 发送任务完成
 	if err != nil {
 		log.Printf("[Client] 任务执行失败: %v", err)
-		c.SendTaskCompleted(&TaskCompletedPayload{
-			JobID:      job.JobID,
-			Status:     "failed",
-			Error:      err.Error(),
-			DurationMs: duration,
+		if job.Origin == JobOriginRemote {
+			c.auditOutcome(MsgTypeRunTask, job.JobID, "failed", err.Error(), duration)
+		}
+		c.emitJobEvent(job, MsgTypeTaskCompleted, &TaskCompletedPayload{
+			JobID:        job.JobID,
+			Status:       "failed",
+			Error:        err.Error(),
+			InvalidInput: invalidInput(err),
+			OptionIssues: optionIssues(err),
+			DurationMs:   duration,
 		})
 	} else {
 		log.Printf("[Client] 任务执行完成，耗时: %dms", duration)
-		c.SendTaskCompleted(&TaskCompletedPayload{
+		if job.Origin == JobOriginRemote {
+			c.auditOutcome(MsgTypeRunTask, job.JobID, "completed", "", duration)
+		}
+		c.emitJobEvent(job, MsgTypeTaskCompleted, &TaskCompletedPayload{
 			JobID:      job.JobID,
 			Status:     "completed",
 			DurationMs: duration,
@@ -220,24 +267,24 @@ func (c *Client) handleStopTask(msg *Message) {
 	var payload StopTaskPayload
 	if err := msg.ParsePayload(&payload); err != nil {
 		log.Printf("[Client] 解析停止任务请求失败: %v", err)
+		c.auditRejected(msg, "invalid_payload", err.Error())
 		return
 	}
 
 	log.Printf("[Client] 收到停止任务请求: %s", payload.JobID)
 
-	// 检查当前任务
-	currentJob := c.GetCurrentJob()
-	if currentJob == nil || currentJob.JobID != payload.JobID {
-		log.Printf("[Client] 任务不存在或已完成")
-		return
-	}
-
 	// 停止任务
-	if c.maaWrapper != nil {
-		if err := c.maaWrapper.StopTask(); err != nil {
-			log.Printf("[Client] 停止任务失败: %v", err)
+	if err := c.StopJob(payload.JobID); err != nil {
+		if errors.Is(err, ErrJobNotFound) {
+			log.Printf("[Client] 任务不存在或已完成")
+			c.auditOutcome(msg.Type, payload.JobID, "ignored", err.Error(), 0)
+			return
 		}
+		log.Printf("[Client] 停止任务失败: %v", err)
+		c.auditOutcome(msg.Type, payload.JobID, "failed", err.Error(), 0)
+		return
 	}
+	c.auditOutcome(msg.Type, payload.JobID, "stopping", "", 0)
 
 	// 任务完成回调会在 RunTask 返回后自动发送
 }
@@ -247,6 +294,7 @@ func (c *Client) handleRequestScreenshot(msg *Message) {
 	var payload RequestScreenshotPayload
 	if err := msg.ParsePayload(&payload); err != nil {
 		log.Printf("[Client] 解析截图请求失败: %v", err)
+		c.auditRejected(msg, "invalid_payload", err.Error())
 		return
 	}
 
@@ -254,6 +302,7 @@ func (c *Client) handleRequestScreenshot(msg *Message) {
 
 	if c.maaWrapper == nil {
 		log.Printf("[Client] MaaWrapper 未初始化，无法截图")
+		c.auditOutcome(msg.Type, payload.RequestID, "failed", "MaaFramework 未初始化", 0)
 		c.SendScreenshot(payload.RequestID, "", 0, 0, "MaaFramework 未初始化")
 		return
 	}
@@ -263,6 +312,7 @@ func (c *Client) handleRequestScreenshot(msg *Message) {
 		imageData, width, height, err := c.maaWrapper.TakeScreenshot()
 		if err != nil {
 			log.Printf("[Client] 截图失败: %v", err)
+			c.auditOutcome(msg.Type, payload.RequestID, "failed", err.Error(), 0)
 			c.SendScreenshot(payload.RequestID, "", 0, 0, err.Error())
 			return
 		}
@@ -272,12 +322,69 @@ func (c *Client) handleRequestScreenshot(msg *Message) {
 
 		// 发送截图
 		c.SendScreenshot(payload.RequestID, base64Image, width, height, "")
+		c.auditOutcome(msg.Type, payload.RequestID, "completed", "", 0)
 
 		log.Printf("[Client] 截图已发送: %dx%d, 大小: %d bytes",
 			width, height, len(imageData))
 	}()
 }
 
+// handleExplainOptions 处理选项解析追踪请求（只解析选项，不执行任务）
+func (c *Client) handleExplainOptions(msg *Message) {
+	var payload ExplainOptionsPayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		log.Printf("[Client] 解析选项追踪请求失败: %v", err)
+		c.auditRejected(msg, "invalid_payload", err.Error())
+		return
+	}
+
+	log.Printf("[Client] 收到选项追踪请求: %s (任务: %s)", payload.RequestID, payload.Task)
+
+	result := &OptionsExplainedPayload{
+		RequestID: payload.RequestID,
+		Task:      payload.Task,
+		Trace:     []OptionTraceStep{},
+	}
+	if c.maaWrapper == nil {
+		result.Error = "MaaFramework 未初始化"
+	} else {
+		explanation, err := c.maaWrapper.ExplainOptions(payload.Task, payload.Options)
+		if explanation != nil {
+			result.Override = explanation.Override
+			result.Trace = explanation.Trace
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+	}
+
+	if result.Error != "" {
+		c.auditOutcome(msg.Type, payload.RequestID, "failed", result.Error, 0)
+	} else {
+		c.auditOutcome(msg.Type, payload.RequestID, "completed", "", 0)
+	}
+	c.SendMessage(MsgTypeOptionsExplained, result)
+}
+
+// handleRequestCapabilities 处理设备能力请求（按指定语言重新上报）
+func (c *Client) handleRequestCapabilities(msg *Message) {
+	var payload RequestCapabilitiesPayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		log.Printf("[Client] 解析能力请求失败: %v", err)
+		c.auditRejected(msg, "invalid_payload", err.Error())
+		return
+	}
+
+	log.Printf("[Client] 收到能力请求: 语言 %q, 全部语言: %v", payload.Lang, payload.All)
+
+	if err := c.sendCapabilities(payload.Lang, payload.All); err != nil {
+		log.Printf("[Client] %v", err)
+		c.auditOutcome(msg.Type, payload.Lang, "failed", err.Error(), 0)
+		return
+	}
+	c.auditOutcome(msg.Type, payload.Lang, "completed", "", 0)
+}
+
 // handleError 处理错误通知
 func (c *Client) handleError(msg *Message) {
 	var payload ErrorPayload
@@ -288,3 +395,218 @@ func (c *Client) handleError(msg *Message) {
 
 	log.Printf("[Client] 服务器错误: %s - %s", payload.Code, payload.Message)
 }
+
+// handleGoingAway 处理服务器即将断开通知
+func (c *Client) handleGoingAway(msg *Message) {
+	var payload GoingAwayPayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		log.Printf("[Client] 解析断开通知失败: %v", err)
+		c.auditRejected(msg, "invalid_payload", err.Error())
+		return
+	}
+
+	log.Printf("[Client] 服务器即将断开: %s (建议 %dms 后重连, 备用地址: %q)",
+		payload.Reason, payload.ReconnectDelayMs, payload.AlternateURL)
+
+	if payload.ReconnectDelayMs > 0 {
+		c.serverRetryDelay = time.Duration(payload.ReconnectDelayMs) * time.Millisecond
+	}
+	// 备用地址会收到设备令牌：ws_urls 中的地址直接使用；
+	// 其他地址需签名校验通过，且主机在 server.allowed_redirect_hosts 中
+	if url := strings.TrimSpace(payload.AlternateURL); url != "" {
+		switch {
+		case c.endpoints.contains(url):
+			c.redirectURL = url
+		case !msg.verified:
+			log.Printf("[Client] 忽略未签名断开通知中的备用地址: %s", url)
+		case !redirectHostAllowed(url, c.config.Server.AllowedRedirectHosts):
+			log.Printf("[Client] 忽略不在 server.allowed_redirect_hosts 中的备用地址: %s", url)
+		default:
+			c.redirectURL = url
+		}
+	}
+
+	c.auditOutcome(msg.Type, "", "disconnecting", payload.Reason, 0)
+
+	// 主动断开，由 Run 按建议延迟重连
+	c.close()
+}
+
+// handleRotateToken 处理令牌轮换
+func (c *Client) handleRotateToken(msg *Message) {
+	var payload RotateTokenPayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		log.Printf("[Client] 解析令牌轮换请求失败: %v", err)
+		c.auditRejected(msg, "invalid_payload", err.Error())
+		return
+	}
+
+	if payload.DeviceToken == "" {
+		log.Printf("[Client] 令牌轮换请求缺少新令牌")
+		c.auditOutcome(msg.Type, "", "failed", "缺少新令牌", 0)
+		c.SendMessage(MsgTypeTokenRotated, &TokenRotatedPayload{
+			DeviceID: c.deviceID,
+			Success:  false,
+			Error:    "缺少新令牌",
+		})
+		return
+	}
+
+	// 新的签名密钥会决定今后哪些指令被视为已签名：只接受签名正确的轮换，
+	// 或在尚无密钥且策略不是 required 时首次下发
+	if payload.SigningSecret != "" && !msg.verified &&
+		(c.signingSecret != "" || c.verifier.mode == SigningRequired) {
+		log.Printf("[Client] 拒绝未签名的签名密钥轮换")
+		c.SendCommandRejected(msg, RejectSignatureRequired, "更换签名密钥的令牌轮换必须签名")
+		return
+	}
+
+	if err := c.replaceToken(payload.DeviceToken, payload.SigningSecret); err != nil {
+		log.Printf("[Client] 保存新令牌失败: %v", err)
+		c.auditOutcome(msg.Type, "", "failed", err.Error(), 0)
+		c.SendMessage(MsgTypeTokenRotated, &TokenRotatedPayload{
+			DeviceID: c.deviceID,
+			Success:  false,
+			Error:    err.Error(),
+		})
+		return
+	}
+
+	c.deviceToken = payload.DeviceToken
+	if payload.SigningSecret != "" {
+		c.signingSecret = payload.SigningSecret
+	}
+	log.Printf("[Client] 设备令牌已轮换")
+	c.auditOutcome(msg.Type, "", "completed", "", 0)
+
+	c.SendMessage(MsgTypeTokenRotated, &TokenRotatedPayload{
+		DeviceID: c.deviceID,
+		Success:  true,
+	})
+}
+
+// handleUnbind 处理服务器发起的解绑
+func (c *Client) handleUnbind(msg *Message) {
+	var payload UnbindPayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		log.Printf("[Client] 解析解绑请求失败: %v", err)
+		c.auditRejected(msg, "invalid_payload", err.Error())
+		return
+	}
+
+	deviceID := c.deviceID
+	log.Printf("[Client] 设备已被服务器解绑: %s", payload.Reason)
+
+	if err := c.clearCredentials(); err != nil {
+		log.Printf("[Client] 清除本地凭证失败: %v", err)
+		c.auditOutcome(msg.Type, "", "failed", err.Error(), 0)
+	} else {
+		c.auditOutcome(msg.Type, "", "completed", payload.Reason, 0)
+	}
+
+	c.SendMessage(MsgTypeUnbound, &UnboundPayload{DeviceID: deviceID})
+	log.Printf("[Client] 已清除本地凭证，请重新绑定设备")
+}
+
+// handleDeviceUnbound 处理服务器对本地解绑的确认，只在 RequestUnbind 等待时有效
+func (c *Client) handleDeviceUnbound(msg *Message) {
+	var payload DeviceUnboundPayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		log.Printf("[Client] 解析解绑确认失败: %v", err)
+		return
+	}
+
+	c.unbindAckMu.Lock()
+	ack := c.unbindAck
+	c.unbindAckMu.Unlock()
+	if ack == nil {
+		log.Printf("[Client] 忽略未请求的解绑确认")
+		return
+	}
+	select {
+	case ack <- payload:
+	default:
+	}
+}
+
+// handleReloadInterface 处理重新加载 interface.json 请求
+// 重新加载成功后设备能力由 Wrapper 的回调重新上报
+func (c *Client) handleReloadInterface(msg *Message) {
+	var payload ReloadInterfacePayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		log.Printf("[Client] 解析重新加载请求失败: %v", err)
+		c.auditRejected(msg, "invalid_payload", err.Error())
+		return
+	}
+
+	log.Printf("[Client] 收到重新加载 interface.json 请求: %s", payload.RequestID)
+
+	result := &InterfaceReloadedPayload{RequestID: payload.RequestID}
+	if c.maaWrapper == nil {
+		result.Error = "MaaFramework 未初始化"
+	} else if reload, err := c.maaWrapper.ReloadInterface(); err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Success = true
+		result.InterfaceReloadResult = *reload
+	}
+
+	if result.Error != "" {
+		c.auditOutcome(msg.Type, payload.RequestID, "failed", result.Error, 0)
+	} else {
+		c.auditOutcome(msg.Type, payload.RequestID, "completed", "", 0)
+	}
+	c.SendMessage(MsgTypeInterfaceReloaded, result)
+}
+
+// handleReloadResource 处理重新加载资源请求，回复每个资源路径的加载结果
+func (c *Client) handleReloadResource(msg *Message) {
+	var payload ReloadResourcePayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		log.Printf("[Client] 解析重新加载资源请求失败: %v", err)
+		c.auditRejected(msg, "invalid_payload", err.Error())
+		return
+	}
+
+	log.Printf("[Client] 收到重新加载资源请求: %s (资源: %s)", payload.RequestID, payload.Resource)
+
+	result := &ResourceReloadedPayload{RequestID: payload.RequestID}
+	if c.maaWrapper == nil {
+		result.Error = "MaaFramework 未初始化"
+	} else {
+		reload, err := c.maaWrapper.ReloadResource(payload.Resource)
+		if reload != nil {
+			result.ResourceReloadResult = *reload
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+		}
+	}
+
+	if result.Error != "" {
+		c.auditOutcome(msg.Type, payload.RequestID, "failed", result.Error, 0)
+	} else {
+		c.auditOutcome(msg.Type, payload.RequestID, "completed", "", 0)
+	}
+	c.SendMessage(MsgTypeResourceReloaded, result)
+}
+
+// invalidInput 从错误链中提取无效输入详情（由 core.InputError 实现）
+func invalidInput(err error) *InvalidInputInfo {
+	var target interface{ InvalidInput() *InvalidInputInfo }
+	if errors.As(err, &target) {
+		return target.InvalidInput()
+	}
+	return nil
+}
+
+// optionIssues 从错误链中提取选项校验问题（由 core.OptionError 实现）
+func optionIssues(err error) []OptionIssue {
+	var target interface{ OptionIssues() []OptionIssue }
+	if errors.As(err, &target) {
+		return target.OptionIssues()
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTP 传输协议（路径相对于服务器地址，消息格式与 WebSocket 完全相同）：
+//
+//	POST {base}/connect  建立会话，响应 {"session_id": "..."}
+//	GET  {base}/poll     长轮询下行消息，响应消息 JSON 数组，无消息时返回 204
+//	GET  {base}/events   SSE 下行消息，每个事件的 data 为一条消息
+//	POST {base}/send     上行一条消息
+//	POST {base}/close    关闭会话
+//
+// 除 connect 外的请求均携带 X-MaaEnd-Session 头
+const sessionHeader = "X-MaaEnd-Session"
+
+// longPollTimeout 单次长轮询请求的最长等待时间（服务器应在此之前返回）
+const longPollTimeout = 90 * time.Second
+
+// pollRetries 长轮询连续失败的最大重试次数，超过后断开会话重连
+const pollRetries = 3
+
+// pollRetryDelay 长轮询重试的初始等待时间，每次重试翻倍
+var pollRetryDelay = 500 * time.Millisecond
+
+// errSessionExpired 服务器会话已失效，重试无意义
+var errSessionExpired = errors.New("会话已失效")
+
+// httpTransport HTTP 长轮询 / SSE 传输
+type httpTransport struct {
+	kind      string
+	base      *url.URL
+	sessionID string
+	header    http.Header
+	timeout   time.Duration
+	client    *http.Client
+
+	incoming chan []byte
+	errCh    chan error
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// connectResponse 建立会话响应
+type connectResponse struct {
+	SessionID string `json:"session_id"`
+}
+
+// dialHTTPTransport 建立 HTTP 长轮询 / SSE 会话
+func dialHTTPTransport(ctx context.Context, kind string, opts transportOptions) (Transport, error) {
+	base, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("服务器地址无效: %w", err)
+	}
+
+	t := &httpTransport{
+		kind:    kind,
+		base:    base,
+		header:  opts.Header.Clone(),
+		timeout: opts.Timeout,
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:     opts.TLSConfig,
+				DialContext:         opts.netDialer(),
+				TLSHandshakeTimeout: opts.Timeout,
+			},
+		},
+		incoming: make(chan []byte, 64),
+		errCh:    make(chan error, 1),
+	}
+	if t.header == nil {
+		t.header = http.Header{}
+	}
+	if t.timeout <= 0 {
+		t.timeout = 10 * time.Second
+	}
+
+	if err := t.openSession(ctx); err != nil {
+		return nil, err
+	}
+
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	if kind == TransportSSE {
+		go t.sseLoop()
+	} else {
+		go t.pollLoop()
+	}
+
+	return t, nil
+}
+
+// openSession 建立会话
+func (t *httpTransport) openSession(ctx context.Context) error {
+	reqCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, t.endpoint("connect"), nil)
+	if err != nil {
+		return err
+	}
+	t.applyHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &HandshakeError{StatusCode: resp.StatusCode, Header: resp.Header, Err: errors.New(resp.Status)}
+	}
+
+	var result connectResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("解析会话响应失败: %w", err)
+	}
+	if result.SessionID == "" {
+		return fmt.Errorf("服务器未返回会话 ID")
+	}
+
+	t.sessionID = result.SessionID
+	return nil
+}
+
+// pollLoop 长轮询下行消息
+// 单次轮询失败（超时、5xx 等）时退避重试，连续失败超过 pollRetries 次或会话失效时才断开
+func (t *httpTransport) pollLoop() {
+	failures := 0
+	for {
+		messages, err := t.poll()
+		if err != nil {
+			if errors.Is(err, errSessionExpired) || t.ctx.Err() != nil || failures >= pollRetries {
+				t.fail(err)
+				return
+			}
+			delay := pollRetryDelay << failures
+			failures++
+			log.Printf("[Client] 长轮询失败，%s 后重试 (%d/%d): %v", delay, failures, pollRetries, err)
+			select {
+			case <-time.After(delay):
+			case <-t.ctx.Done():
+				t.fail(err)
+				return
+			}
+			continue
+		}
+		failures = 0
+
+		for _, msg := range messages {
+			if !t.deliver(msg) {
+				return
+			}
+		}
+	}
+}
+
+// poll 执行一次长轮询
+func (t *httpTransport) poll() ([]json.RawMessage, error) {
+	reqCtx, cancel := context.WithTimeout(t.ctx, longPollTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, t.endpoint("poll"), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.applyHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var messages []json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&messages); err != nil {
+			return nil, fmt.Errorf("解析轮询响应失败: %w", err)
+		}
+		return messages, nil
+	case http.StatusNoContent:
+		return nil, nil
+	case http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("%w: %s", errSessionExpired, resp.Status)
+	default:
+		return nil, fmt.Errorf("轮询失败: %s", resp.Status)
+	}
+}
+
+// sseLoop 通过 SSE 接收下行消息
+func (t *httpTransport) sseLoop() {
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, t.endpoint("events"), nil)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	t.applyHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.fail(fmt.Errorf("订阅事件流失败: %s", resp.Status))
+		return
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// 空行表示一个事件结束
+			if len(data) > 0 {
+				if !t.deliver([]byte(strings.Join(data, "\n"))) {
+					return
+				}
+				data = data[:0]
+			}
+		case strings.HasPrefix(line, ":"):
+			// 注释行（保活）
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.fail(err)
+		return
+	}
+	t.fail(io.EOF)
+}
+
+// deliver 投递下行消息，传输已关闭时返回 false
+func (t *httpTransport) deliver(msg []byte) bool {
+	select {
+	case t.incoming <- msg:
+		return true
+	case <-t.ctx.Done():
+		return false
+	}
+}
+
+// fail 记录下行通道错误
+func (t *httpTransport) fail(err error) {
+	if t.ctx.Err() != nil {
+		err = io.EOF
+	}
+	select {
+	case t.errCh <- err:
+	default:
+	}
+}
+
+// ReadMessage 读取消息
+func (t *httpTransport) ReadMessage() ([]byte, error) {
+	select {
+	case msg := <-t.incoming:
+		return msg, nil
+	case err := <-t.errCh:
+		// select 随机选择就绪的分支：下行通道结束前已收到的消息先返回，错误留到消息读完后再返回
+		select {
+		case msg := <-t.incoming:
+			t.fail(err)
+			return msg, nil
+		default:
+		}
+		return nil, err
+	case <-t.ctx.Done():
+		return nil, io.EOF
+	}
+}
+
+// WriteMessage 发送消息
+func (t *httpTransport) WriteMessage(data []byte) error {
+	reqCtx, cancel := context.WithTimeout(t.ctx, t.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, t.endpoint("send"), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	t.applyHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("发送消息失败: %s", resp.Status)
+	}
+	return nil
+}
+
+// Close 关闭会话
+func (t *httpTransport) Close() error {
+	t.closeOnce.Do(func() {
+		t.cancel()
+
+		// 通知服务器关闭会话（尽力而为）
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint("close"), nil)
+			if err != nil {
+				return
+			}
+			t.applyHeaders(req)
+			if resp, err := t.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}()
+	})
+	return nil
+}
+
+// endpoint 拼接接口地址
+func (t *httpTransport) endpoint(name string) string {
+	u := *t.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + name
+	return u.String()
+}
+
+// applyHeaders 设置公共请求头
+func (t *httpTransport) applyHeaders(req *http.Request) {
+	for k, v := range t.header {
+		req.Header[k] = v
+	}
+	if t.sessionID != "" {
+		req.Header.Set(sessionHeader, t.sessionID)
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+)
+
+// 任务来源
+const (
+	JobOriginRemote = "remote" // 服务器下发
+	JobOriginLocal  = "local"  // 本地 API
+)
+
+// 任务操作错误
+var (
+	ErrDeviceBusy  = errors.New("设备忙碌")
+	ErrMaaNotReady = errors.New("MaaFramework 未初始化")
+	ErrJobNotFound = errors.New("任务不存在或已完成")
+)
+
+// SetJobEventHandler 设置任务事件回调（task_status、task_log、task_completed），用于本地 API 推送
+func (c *Client) SetJobEventHandler(handler func(*Message)) {
+	c.onJobEvent = handler
+}
+
+// TryStartJob 占用任务槽位，已有任务在执行时返回 false
+func (c *Client) TryStartJob(job *Job) bool {
+	c.currentJobMu.Lock()
+	defer c.currentJobMu.Unlock()
+	if c.currentJob != nil {
+		return false
+	}
+	c.currentJob = job
+	return true
+}
+
+// StartJob 启动任务（异步执行），服务器下发和本地 API 共用
+func (c *Client) StartJob(job *Job) error {
+	if c.maaWrapper == nil {
+		return ErrMaaNotReady
+	}
+
+	job.StartTime = time.Now()
+	job.Status = "running"
+	if !c.TryStartJob(job) {
+		return ErrDeviceBusy
+	}
+
+	log.Printf("[Client] 开始执行任务: %s (来源: %s)", job.JobID, job.Origin)
+
+	// 发送任务开始状态
+	c.emitJobEvent(job, MsgTypeTaskStatus, &TaskStatusPayload{
+		JobID:       job.JobID,
+		Status:      "running",
+		CurrentTask: "",
+		Progress:    JobProgress{Completed: 0, Total: len(job.Tasks)},
+		Message:     "任务开始执行",
+	})
+
+	// 异步执行任务
+	go c.executeTask(job)
+	return nil
+}
+
+// StopJob 停止指定任务
+func (c *Client) StopJob(jobID string) error {
+	currentJob := c.GetCurrentJob()
+	if currentJob == nil || currentJob.JobID != jobID {
+		return ErrJobNotFound
+	}
+	if c.maaWrapper == nil {
+		return ErrMaaNotReady
+	}
+
+	// 任务完成事件会在 RunTask 返回后自动发送
+	return c.maaWrapper.StopTask()
+}
+
+// emitJobEvent 发送任务事件：服务器下发的任务上报给服务器，所有任务都推送给本地订阅者
+func (c *Client) emitJobEvent(job *Job, msgType string, payload interface{}) {
+	msg, err := NewMessage(msgType, payload)
+	if err != nil {
+		log.Printf("[Client] 序列化任务事件失败: %v", err)
+		return
+	}
+
+	if job.Origin != JobOriginLocal {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			log.Printf("[Client] 序列化任务事件失败: %v", err)
+			return
+		}
+		c.Send(data)
+	}
+
+	if c.onJobEvent != nil {
+		c.onJobEvent(msg)
+	}
+}
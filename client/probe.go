@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Probe 尝试与服务器地址建立一次传输连接（不进行认证），返回耗时
+// 用于诊断网络、代理与 TLS 配置；连接成功后立即关闭
+func (c *Client) Probe(ctx context.Context, serverURL string) (time.Duration, error) {
+	tlsConfig, err := buildTLSConfig(c.config.Server.TLS)
+	if err != nil {
+		return 0, fmt.Errorf("TLS 配置错误: %w", err)
+	}
+
+	kind, dialURL, err := resolveTransport(c.config.Server.Transport, serverURL)
+	if err != nil {
+		return 0, err
+	}
+
+	dial, err := c.proxyDialer(dialURL)
+	if err != nil {
+		return 0, err
+	}
+
+	if c.config.Server.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Server.ConnectTimeout)
+		defer cancel()
+	}
+
+	header := http.Header{}
+	header.Set("User-Agent", "MaaEnd-Client/1.0")
+
+	start := time.Now()
+	conn, err := dialTransport(ctx, kind, transportOptions{
+		URL:         dialURL,
+		Header:      header,
+		Timeout:     c.config.Server.ConnectTimeout,
+		TLSConfig:   tlsConfig,
+		DialContext: dial,
+	})
+	if err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(start)
+	conn.Close()
+	return elapsed, nil
+}
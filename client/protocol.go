@@ -9,26 +9,42 @@ import (
 
 // Client -> Server 消息类型
 const (
-	MsgTypeRegister      = "register"       // 设备注册
-	MsgTypeAuth          = "auth"           // 设备认证
-	MsgTypePing          = "ping"           // 心跳
-	MsgTypeCapabilities  = "capabilities"   // 设备能力上报
-	MsgTypeTaskStatus    = "task_status"    // 任务状态上报
-	MsgTypeTaskLog       = "task_log"       // 任务日志上报
-	MsgTypeTaskCompleted = "task_completed" // 任务完成上报
-	MsgTypeScreenshot    = "screenshot"     // 截图上报
+	MsgTypeRegister          = "register"           // 设备注册
+	MsgTypeAuth              = "auth"               // 设备认证
+	MsgTypePing              = "ping"               // 心跳
+	MsgTypeCapabilities      = "capabilities"       // 设备能力上报
+	MsgTypeTaskStatus        = "task_status"        // 任务状态上报
+	MsgTypeTaskLog           = "task_log"           // 任务日志上报
+	MsgTypeTaskCompleted     = "task_completed"     // 任务完成上报
+	MsgTypeScreenshot        = "screenshot"         // 截图上报
+	MsgTypeCommandRejected   = "command_rejected"   // 指令被拒绝
+	MsgTypeTokenRotated      = "token_rotated"      // 令牌轮换结果
+	MsgTypeUnbindDevice      = "unbind_device"      // 本地发起解绑
+	MsgTypeUnbound           = "unbound"            // 已按服务器要求解绑
+	MsgTypeOptionsExplained  = "options_explained"  // 选项解析追踪结果
+	MsgTypeCapabilitiesDiff  = "capabilities_diff"  // 设备能力差异
+	MsgTypeInterfaceReloaded = "interface_reloaded" // 项目接口重新加载结果
+	MsgTypeResourceReloaded  = "resource_reloaded"  // 资源重新加载结果
 )
 
 // Server -> Client 消息类型
 const (
-	MsgTypeRegistered        = "registered"         // 注册成功
-	MsgTypeAuthenticated     = "authenticated"      // 认证成功
-	MsgTypeAuthFailed        = "auth_failed"        // 认证失败
-	MsgTypePong              = "pong"               // 心跳响应
-	MsgTypeRunTask           = "run_task"           // 下发任务
-	MsgTypeStopTask          = "stop_task"          // 停止任务
-	MsgTypeRequestScreenshot = "request_screenshot" // 请求截图
-	MsgTypeError             = "error"              // 错误通知
+	MsgTypeRegistered          = "registered"           // 注册成功
+	MsgTypeAuthenticated       = "authenticated"        // 认证成功
+	MsgTypeAuthFailed          = "auth_failed"          // 认证失败
+	MsgTypePong                = "pong"                 // 心跳响应
+	MsgTypeRunTask             = "run_task"             // 下发任务
+	MsgTypeStopTask            = "stop_task"            // 停止任务
+	MsgTypeRequestScreenshot   = "request_screenshot"   // 请求截图
+	MsgTypeError               = "error"                // 错误通知
+	MsgTypeGoingAway           = "going_away"           // 服务器即将断开
+	MsgTypeRotateToken         = "rotate_token"         // 轮换设备令牌
+	MsgTypeUnbind              = "unbind"               // 解绑设备
+	MsgTypeExplainOptions      = "explain_options"      // 请求选项解析追踪
+	MsgTypeRequestCapabilities = "request_capabilities" // 请求重新上报设备能力
+	MsgTypeReloadInterface     = "reload_interface"     // 重新加载 interface.json
+	MsgTypeReloadResource      = "reload_resource"      // 重新加载资源
+	MsgTypeDeviceUnbound       = "device_unbound"       // 本地发起解绑的确认
 )
 
 // ==================== 基础消息结构 ====================
@@ -38,6 +54,10 @@ type Message struct {
 	Type      string          `json:"type"`
 	Payload   json.RawMessage `json:"payload,omitempty"`
 	Timestamp time.Time       `json:"timestamp"`
+	Nonce     string          `json:"nonce,omitempty"`     // 签名指令的一次性随机值
+	Signature string          `json:"signature,omitempty"` // 服务器指令签名（HMAC-SHA256，base64）
+
+	verified bool // 签名校验通过（由 commandVerifier.verify 设置）
 }
 
 // NewMessage 创建新消息
@@ -82,6 +102,9 @@ type AuthPayload struct {
 	DeviceToken   string `json:"device_token"`
 	MaaEndVersion string `json:"maaend_version,omitempty"` // MaaEnd 版本
 	ClientVersion string `json:"client_version,omitempty"` // Client 版本
+
+	CapabilitiesHash     string `json:"capabilities_hash,omitempty"`      // 当前设备能力的哈希
+	CapabilitiesBaseHash string `json:"capabilities_base_hash,omitempty"` // 上次上报的能力哈希（可作为差异的基准）
 }
 
 // CapabilitiesPayload 设备能力上报负载
@@ -89,29 +112,69 @@ type CapabilitiesPayload struct {
 	Tasks       []TaskInfo `json:"tasks"`
 	Controllers []string   `json:"controllers"`
 	Resources   []string   `json:"resources"`
+
+	OptionDependencies map[string][]string `json:"option_dependencies,omitempty"` // 选项 -> 其 case 中嵌套的选项
+
+	Hash      string                `json:"hash,omitempty"`      // 内容哈希（CapabilitiesHash）
+	Language  string                `json:"language,omitempty"`  // tasks 中名称和说明使用的语言
+	Languages []string              `json:"languages,omitempty"` // interface.json 声明的语言
+	Localized map[string][]TaskInfo `json:"localized,omitempty"` // 请求全部语言时，按语言代码给出的任务信息
+}
+
+// CapabilitiesDiffPayload 设备能力差异上报负载（相对于 base_hash 对应的版本）
+type CapabilitiesDiffPayload struct {
+	BaseHash     string     `json:"base_hash"`
+	Hash         string     `json:"hash"`
+	TaskOrder    []string   `json:"task_order"` // 当前所有任务名称（按顺序）
+	AddedTasks   []TaskInfo `json:"added_tasks,omitempty"`
+	ChangedTasks []TaskInfo `json:"changed_tasks,omitempty"` // 整体替换同名任务
+	RemovedTasks []string   `json:"removed_tasks,omitempty"`
+
+	Controllers        []string            `json:"controllers"`
+	Resources          []string            `json:"resources"`
+	OptionDependencies map[string][]string `json:"option_dependencies,omitempty"`
+	Language           string              `json:"language,omitempty"`
+	Languages          []string            `json:"languages,omitempty"`
 }
 
 // TaskInfo 任务信息
 type TaskInfo struct {
-	Name        string       `json:"name"`
-	Label       string       `json:"label"`
-	Description string       `json:"description,omitempty"`
-	Options     []OptionInfo `json:"options,omitempty"`
+	Name         string       `json:"name"`
+	Label        string       `json:"label"`
+	Description  string       `json:"description,omitempty"`
+	DefaultCheck bool         `json:"default_check"`
+	Controllers  []string     `json:"controllers,omitempty"` // 支持的控制器，为空表示全部
+	Resources    []string     `json:"resources,omitempty"`   // 支持的资源，为空表示全部
+	Options      []OptionInfo `json:"options,omitempty"`
 }
 
 // OptionInfo 选项信息
 type OptionInfo struct {
-	Name        string     `json:"name"`
-	Type        string     `json:"type"`
-	Label       string     `json:"label"`
-	Cases       []CaseInfo `json:"cases,omitempty"`
-	DefaultCase string     `json:"default_case,omitempty"`
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Label       string      `json:"label"`
+	Description string      `json:"description,omitempty"`
+	Cases       []CaseInfo  `json:"cases,omitempty"`
+	DefaultCase string      `json:"default_case,omitempty"`
+	Inputs      []InputInfo `json:"inputs,omitempty"`
+	Recursive   bool        `json:"recursive,omitempty"` // 循环引用，不再展开（详见 option_dependencies）
 }
 
 // CaseInfo 选项分支
 type CaseInfo struct {
-	Name  string `json:"name"`
-	Label string `json:"label"`
+	Name    string       `json:"name"`
+	Label   string       `json:"label"`
+	Options []OptionInfo `json:"options,omitempty"` // 选中该分支时生效的嵌套选项
+}
+
+// InputInfo 输入选项的输入字段
+type InputInfo struct {
+	Name         string      `json:"name"`
+	Label        string      `json:"label"`
+	Description  string      `json:"description,omitempty"`
+	PipelineType string      `json:"pipeline_type,omitempty"`
+	Verify       string      `json:"verify,omitempty"`
+	Default      interface{} `json:"default,omitempty"`
 }
 
 // TaskStatusPayload 任务状态上报负载
@@ -140,10 +203,38 @@ type TaskLogPayload struct {
 
 // TaskCompletedPayload 任务完成上报负载
 type TaskCompletedPayload struct {
-	JobID      string `json:"job_id"`
-	Status     string `json:"status"`
-	Error      string `json:"error,omitempty"`
-	DurationMs int64  `json:"duration_ms"`
+	JobID        string            `json:"job_id"`
+	Status       string            `json:"status"`
+	Error        string            `json:"error,omitempty"`
+	InvalidInput *InvalidInputInfo `json:"invalid_input,omitempty"` // 因输入选项无效而失败时的详情
+	OptionIssues []OptionIssue     `json:"option_issues,omitempty"` // 严格模式下因选项校验失败时的详情
+	DurationMs   int64             `json:"duration_ms"`
+}
+
+// InvalidInputInfo 无效的输入选项
+type InvalidInputInfo struct {
+	Task   string `json:"task,omitempty"`
+	Option string `json:"option"`
+	Input  string `json:"input"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// 选项问题代码
+const (
+	OptionIssueUnknownOption = "unknown_option" // 选项未在任务（含 case 嵌套选项）中声明
+	OptionIssueUnknownInput  = "unknown_input"  // 输入选项中包含未声明的输入
+	OptionIssueInvalidType   = "invalid_type"   // 值的类型与选项类型不符
+	OptionIssueUnknownCase   = "unknown_case"   // 选中的 case 不存在
+)
+
+// OptionIssue 任务选项校验问题
+type OptionIssue struct {
+	Task    string      `json:"task"`
+	Option  string      `json:"option"`
+	Code    string      `json:"code"`
+	Value   interface{} `json:"value,omitempty"`
+	Message string      `json:"message"`
 }
 
 // ScreenshotPayload 截图上报负载
@@ -155,18 +246,144 @@ type ScreenshotPayload struct {
 	Error       string `json:"error,omitempty"`
 }
 
+// CommandRejectedPayload 指令被拒绝负载
+type CommandRejectedPayload struct {
+	Type      string `json:"type"`                 // 被拒绝的指令类型
+	Code      string `json:"code"`                 // 拒绝原因代码
+	Message   string `json:"message"`              // 拒绝原因说明
+	JobID     string `json:"job_id,omitempty"`     // 指令中的任务 ID（如有）
+	RequestID string `json:"request_id,omitempty"` // 指令中的请求 ID（如有）
+}
+
+// TokenRotatedPayload 令牌轮换结果负载
+type TokenRotatedPayload struct {
+	DeviceID string `json:"device_id"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+}
+
+// UnbindDevicePayload 本地发起解绑负载
+type UnbindDevicePayload struct {
+	DeviceToken string `json:"device_token"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// DeviceUnboundPayload 本地发起解绑的确认负载
+type DeviceUnboundPayload struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// UnboundPayload 解绑完成负载
+type UnboundPayload struct {
+	DeviceID string `json:"device_id"`
+}
+
+// InterfaceReloadedPayload 项目接口重新加载结果负载
+type InterfaceReloadedPayload struct {
+	RequestID string `json:"request_id"`
+	Success   bool   `json:"success"`
+	InterfaceReloadResult
+	Error string `json:"error,omitempty"`
+}
+
+// InterfaceReloadResult 项目接口重新加载结果
+type InterfaceReloadResult struct {
+	Pending bool     `json:"pending,omitempty"` // 任务执行中，将在任务结束后重新加载
+	Version string   `json:"version,omitempty"`
+	Tasks   int      `json:"tasks,omitempty"`  // 重新加载后的任务数
+	Errors  []string `json:"errors,omitempty"` // interface.json 校验错误（不阻止重新加载）
+}
+
+// ResourceReloadedPayload 资源重新加载结果负载
+type ResourceReloadedPayload struct {
+	RequestID string `json:"request_id"`
+	Success   bool   `json:"success"`
+	ResourceReloadResult
+	Error string `json:"error,omitempty"`
+}
+
+// ResourceReloadResult 资源重新加载结果
+type ResourceReloadResult struct {
+	Resource string                 `json:"resource,omitempty"`
+	Pending  bool                   `json:"pending,omitempty"` // 任务执行中，将在下次任务前重新加载
+	Bundles  []ResourceBundleResult `json:"bundles,omitempty"` // 每个资源路径的加载结果
+}
+
+// ResourceBundleResult 单个资源路径（PostBundle）的加载结果
+type ResourceBundleResult struct {
+	Path    string `json:"path"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// OptionsExplainedPayload 选项解析追踪结果负载
+type OptionsExplainedPayload struct {
+	RequestID string                 `json:"request_id"`
+	Task      string                 `json:"task"`
+	Override  map[string]interface{} `json:"override,omitempty"` // 合并后的 pipeline_override
+	Trace     []OptionTraceStep      `json:"trace"`
+	Error     string                 `json:"error,omitempty"` // 解析失败时 trace 为失败前的步骤
+}
+
+// OptionExplanation 选项解析结果及追踪记录
+type OptionExplanation struct {
+	Override map[string]interface{} `json:"override"`
+	Trace    []OptionTraceStep      `json:"trace"`
+}
+
+// 追踪步骤类型
+const (
+	TraceStepTask    = "task"    // 任务级 pipeline_override
+	TraceStepCase    = "case"    // select / switch / checkbox 选中的 case
+	TraceStepInput   = "input"   // 输入选项
+	TraceStepSkipped = "skipped" // 选项或 case 未生效
+)
+
+// case 选择原因
+const (
+	CaseReasonUser        = "user"         // 用户指定
+	CaseReasonDefaultCase = "default_case" // 选项的 default_case
+	CaseReasonDefault     = "default"      // 选项的 default（旧字段）
+	CaseReasonFirst       = "first"        // 未指定默认值时使用第一个 case
+)
+
+// OptionTraceStep 选项解析的一个步骤，按解析顺序排列
+type OptionTraceStep struct {
+	Step        string                 `json:"step"`
+	Option      string                 `json:"option,omitempty"`
+	Parent      string                 `json:"parent,omitempty"` // 嵌套选项所属的 选项/case
+	Depth       int                    `json:"depth"`
+	Case        string                 `json:"case,omitempty"`
+	Reason      string                 `json:"reason,omitempty"`
+	Inputs      map[string]interface{} `json:"inputs,omitempty"`
+	Keys        []string               `json:"keys,omitempty"` // 本步骤写入的键（节点.字段）
+	Overwritten []OverwrittenKey       `json:"overwritten,omitempty"`
+	Message     string                 `json:"message,omitempty"`
+}
+
+// OverwrittenKey 被后续步骤覆盖的键
+type OverwrittenKey struct {
+	Key          string      `json:"key"`
+	Previous     interface{} `json:"previous"`
+	Value        interface{} `json:"value"`
+	PreviousStep int         `json:"previous_step"` // 之前写入该键的步骤下标
+}
+
 // ==================== Server -> Client 消息负载 ====================
 
 // RegisteredPayload 注册成功响应负载
 type RegisteredPayload struct {
-	DeviceID    string `json:"device_id"`
-	DeviceToken string `json:"device_token"`
+	DeviceID      string `json:"device_id"`
+	DeviceToken   string `json:"device_token"`
+	SigningSecret string `json:"signing_secret"` // 指令签名密钥（与设备令牌不同，客户端不会发送）
 }
 
 // AuthenticatedPayload 认证成功响应负载
 type AuthenticatedPayload struct {
 	DeviceID     string `json:"device_id"`
 	UserNickname string `json:"user_nickname"`
+	Capabilities string `json:"capabilities,omitempty"` // 能力上报方式: unchanged, diff, full（为空时为 full）
 }
 
 // AuthFailedPayload 认证失败响应负载
@@ -181,6 +398,8 @@ type RunTaskPayload struct {
 	Controller string        `json:"controller"`
 	Resource   string        `json:"resource"`
 	Tasks      []RunTaskItem `json:"tasks"`
+	// StrictOptions 覆盖本地 maaend.strict_options（为空使用本地配置）
+	StrictOptions *bool `json:"strict_options,omitempty"`
 }
 
 // RunTaskItem 任务项
@@ -205,6 +424,48 @@ type ErrorPayload struct {
 	Message string `json:"message"`
 }
 
+// GoingAwayPayload 服务器即将断开通知负载
+type GoingAwayPayload struct {
+	Reason           string `json:"reason,omitempty"`
+	ReconnectDelayMs int64  `json:"reconnect_delay_ms,omitempty"` // 建议的重连延迟
+	AlternateURL     string `json:"alternate_url,omitempty"`      // 下次连接使用的备用地址
+}
+
+// RotateTokenPayload 轮换设备令牌负载
+type RotateTokenPayload struct {
+	DeviceToken   string `json:"device_token"`
+	SigningSecret string `json:"signing_secret,omitempty"` // 新的指令签名密钥，为空时保留原密钥
+}
+
+// UnbindPayload 解绑设备负载
+type UnbindPayload struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// RequestCapabilitiesPayload 请求设备能力负载
+type RequestCapabilitiesPayload struct {
+	Lang string `json:"lang,omitempty"` // 语言代码，为空使用本地配置的 maaend.language
+	All  bool   `json:"all,omitempty"`  // 同时返回 interface.json 声明的所有语言
+}
+
+// ReloadInterfacePayload 重新加载 interface.json 请求负载
+type ReloadInterfacePayload struct {
+	RequestID string `json:"request_id"`
+}
+
+// ReloadResourcePayload 重新加载资源请求负载
+type ReloadResourcePayload struct {
+	RequestID string `json:"request_id"`
+	Resource  string `json:"resource,omitempty"` // 资源名称，为空时重新加载当前资源
+}
+
+// ExplainOptionsPayload 选项解析追踪请求负载
+type ExplainOptionsPayload struct {
+	RequestID string                 `json:"request_id"`
+	Task      string                 `json:"task"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+}
+
 // ==================== 辅助函数 ====================
 
 // MarshalMessage 序列化消息
@@ -0,0 +1,150 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxyDirect 配置为此值时不使用任何代理（包括环境变量）
+const ProxyDirect = "direct"
+
+// dialContextFunc 拨号函数
+type dialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// resolveProxy 确定连接 target 时使用的代理
+// setting 为空时读取 HTTPS_PROXY / HTTP_PROXY / NO_PROXY 环境变量；返回 nil 表示直连
+func resolveProxy(setting string, target *url.URL) (proxyURL *url.URL, source string, err error) {
+	setting = strings.TrimSpace(setting)
+
+	if strings.EqualFold(setting, ProxyDirect) {
+		return nil, "", nil
+	}
+
+	if setting != "" {
+		u, err := url.Parse(setting)
+		if err != nil {
+			return nil, "", fmt.Errorf("代理地址无效: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, "", fmt.Errorf("不支持的代理协议: %s (可选: http, https, socks5)", u.Scheme)
+		}
+		if u.Host == "" {
+			return nil, "", fmt.Errorf("代理地址缺少主机: %s", setting)
+		}
+		return u, "配置", nil
+	}
+
+	// 环境变量按 HTTP 语义匹配，ws/wss 分别对应 http/https
+	reqURL := *target
+	switch reqURL.Scheme {
+	case "ws":
+		reqURL.Scheme = "http"
+	case "wss":
+		reqURL.Scheme = "https"
+	}
+	u, err := http.ProxyFromEnvironment(&http.Request{URL: &reqURL})
+	if err != nil {
+		return nil, "", fmt.Errorf("解析代理环境变量失败: %w", err)
+	}
+	if u == nil {
+		return nil, "", nil
+	}
+	return u, "环境变量", nil
+}
+
+// newProxyDialer 创建经由代理的拨号函数
+func newProxyDialer(proxyURL *url.URL, forward *net.Dialer) (dialContextFunc, error) {
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		d, err := proxy.FromURL(proxyURL, forward)
+		if err != nil {
+			return nil, fmt.Errorf("创建 SOCKS5 代理失败: %w", err)
+		}
+		if cd, ok := d.(proxy.ContextDialer); ok {
+			return cd.DialContext, nil
+		}
+		return func(_ context.Context, network, addr string) (net.Conn, error) {
+			return d.Dial(network, addr)
+		}, nil
+	case "http", "https":
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialHTTPConnect(ctx, forward, proxyURL, addr)
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的代理协议: %s", proxyURL.Scheme)
+	}
+}
+
+// dialHTTPConnect 通过 HTTP(S) 代理的 CONNECT 方法建立隧道
+func dialHTTPConnect(ctx context.Context, forward *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		if proxyURL.Scheme == "https" {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "443")
+		} else {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "80")
+		}
+	}
+
+	conn, err := forward.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("连接代理失败: %w", err)
+	}
+
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("代理 TLS 握手失败: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credential := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credential)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("发送 CONNECT 请求失败: %w", err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("读取代理响应失败: %w", err)
+	}
+
+	// 隧道建立后连接直接用于后续数据，不读取响应体
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("代理拒绝 CONNECT 请求: %s", resp.Status)
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// newConnectProxy 启动一个要求 Basic 认证的 CONNECT 代理
+func newConnectProxy(t *testing.T, wantAuth string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "only CONNECT", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != wantAuth {
+			http.Error(w, "auth required", http.StatusProxyAuthRequired)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		hijacker := w.(http.Hijacker)
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := hijacker.Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPConnectProxy(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, msg, err := conn.ReadMessage()
+		if err == nil {
+			conn.WriteMessage(websocket.TextMessage, msg)
+		}
+	}))
+	defer backend.Close()
+
+	// user:pass 的 Basic 认证
+	proxySrv := newConnectProxy(t, "Basic dXNlcjpwYXNz")
+	proxyURL, _ := url.Parse(strings.Replace(proxySrv.URL, "http://", "http://user:pass@", 1))
+
+	dial, err := newProxyDialer(proxyURL, &net.Dialer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dialer := websocket.Dialer{NetDialContext: dial}
+
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(backend.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("经代理连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+	if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != "hello" {
+		t.Fatalf("回显失败: %q, %v", msg, err)
+	}
+
+	// 错误的认证信息应被代理拒绝
+	badURL, _ := url.Parse(proxySrv.URL)
+	badDial, _ := newProxyDialer(badURL, &net.Dialer{})
+	if _, err := badDial(context.Background(), "tcp", strings.TrimPrefix(backend.URL, "http://")); err == nil {
+		t.Fatal("缺少认证时代理应拒绝连接")
+	}
+}
+
+func TestResolveProxySetting(t *testing.T) {
+	target, _ := url.Parse("wss://example.com/ws")
+
+	if u, _, err := resolveProxy("direct", target); err != nil || u != nil {
+		t.Fatalf("direct 应直连, got %v, %v", u, err)
+	}
+	if u, source, err := resolveProxy("socks5://127.0.0.1:1080", target); err != nil || u.Host != "127.0.0.1:1080" || source != "配置" {
+		t.Fatalf("got %v, %s, %v", u, source, err)
+	}
+	if _, _, err := resolveProxy("ftp://proxy", target); err == nil {
+		t.Fatal("不支持的协议应报错")
+	}
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxServerRetryDelay 服务器建议的重连延迟上限，防止异常值让客户端长期离线
+const maxServerRetryDelay = 10 * time.Minute
+
+// backoffDelay 计算第 attempt 次重连的延迟（带全抖动的指数退避）
+// 上限为 min(maxDelay, minDelay*2^(attempt-1))，实际延迟在 [minDelay, 上限] 内均匀随机，
+// 避免服务器重启后所有客户端同时重连
+func backoffDelay(attempt int, minDelay, maxDelay time.Duration) time.Duration {
+	if minDelay <= 0 {
+		minDelay = time.Second
+	}
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	ceiling := minDelay
+	for i := 1; i < attempt && ceiling < maxDelay; i++ {
+		ceiling *= 2
+	}
+	if ceiling > maxDelay {
+		ceiling = maxDelay
+	}
+
+	spread := ceiling - minDelay
+	if spread <= 0 {
+		return minDelay
+	}
+	return minDelay + time.Duration(rand.Int64N(int64(spread)+1))
+}
+
+// serverDirectedDelay 处理服务器建议的延迟：不早于建议值，并附加最多 20% 的抖动
+func serverDirectedDelay(suggested, minDelay time.Duration) time.Duration {
+	if suggested < minDelay {
+		suggested = minDelay
+	}
+	if suggested > maxServerRetryDelay {
+		suggested = maxServerRetryDelay
+	}
+	jitter := suggested / 5
+	if jitter <= 0 {
+		return suggested
+	}
+	return suggested + time.Duration(rand.Int64N(int64(jitter)+1))
+}
+
+// parseRetryAfter 解析 HTTP Retry-After 头，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		delay := at.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// isRetryAfterStatus 检查握手响应状态码是否应遵循 Retry-After
+func isRetryAfterStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelayBounds(t *testing.T) {
+	minDelay := time.Second
+	maxDelay := 30 * time.Second
+
+	cases := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{5, 16 * time.Second},
+		{6, 30 * time.Second},
+		{50, 30 * time.Second},
+	}
+
+	for _, tc := range cases {
+		for i := 0; i < 100; i++ {
+			d := backoffDelay(tc.attempt, minDelay, maxDelay)
+			if d < minDelay || d > tc.ceiling {
+				t.Fatalf("attempt %d: 延迟 %s 超出范围 [%s, %s]", tc.attempt, d, minDelay, tc.ceiling)
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 120 * time.Second, true},
+		{" 5 ", 5 * time.Second, true},
+		{"Sun, 01 Feb 2026 12:00:30 GMT", 30 * time.Second, true},
+		{"Sun, 01 Feb 2026 11:00:00 GMT", 0, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, tc := range cases {
+		got, ok := parseRetryAfter(tc.value, now)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("parseRetryAfter(%q) = %s, %v; want %s, %v", tc.value, got, ok, tc.want, tc.ok)
+		}
+	}
+}
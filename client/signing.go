@@ -0,0 +1,170 @@
+package client
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 指令签名策略
+const (
+	SigningOff      = "off"      // 不校验签名
+	SigningOptional = "optional" // 有签名时校验，未签名指令仍接受
+	SigningRequired = "required" // 拒绝未签名指令
+)
+
+// 指令拒绝错误码
+const (
+	RejectSignatureRequired = "signature_required"      // 指令未签名
+	RejectSignatureInvalid  = "signature_invalid"       // 签名不正确
+	RejectTimestampInvalid  = "timestamp_out_of_window" // 时间戳超出允许范围
+	RejectNonceReplayed     = "nonce_replayed"          // nonce 重复（重放）
+)
+
+// signingKeyInfo HKDF 派生签名密钥使用的上下文信息
+const signingKeyInfo = "maaend-client/command-signing/v1"
+
+// signedCommandTypes 需要签名校验的服务器指令
+var signedCommandTypes = map[string]bool{
+	MsgTypeRunTask:             true,
+	MsgTypeStopTask:            true,
+	MsgTypeRequestScreenshot:   true,
+	MsgTypeGoingAway:           true,
+	MsgTypeRotateToken:         true,
+	MsgTypeUnbind:              true,
+	MsgTypeExplainOptions:      true,
+	MsgTypeRequestCapabilities: true,
+	MsgTypeReloadInterface:     true,
+	MsgTypeReloadResource:      true,
+}
+
+// CommandRejection 指令被拒绝的原因
+type CommandRejection struct {
+	Code    string
+	Message string
+}
+
+func (r *CommandRejection) Error() string {
+	return r.Code + ": " + r.Message
+}
+
+// credentialMessageTypes 会清除本地凭证的协议消息：与服务器指令一样校验签名，但不记录审计、不受指令权限策略限制
+var credentialMessageTypes = map[string]bool{
+	MsgTypeAuthFailed: true,
+}
+
+// deriveSigningKey 从注册（或令牌轮换）时下发的指令签名密钥派生 HMAC 密钥
+// 不使用设备令牌：令牌会在 auth、unbind_device 中发送，能读取连接的一方即可伪造签名
+func deriveSigningKey(signingSecret string) []byte {
+	if signingSecret == "" {
+		return nil
+	}
+	key, err := hkdf.Key(sha256.New, []byte(signingSecret), nil, signingKeyInfo, 32)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+// signingContent 构造签名内容：type \n 毫秒时间戳 \n nonce \n payload 原文
+func signingContent(msg *Message) []byte {
+	content := make([]byte, 0, len(msg.Type)+len(msg.Nonce)+len(msg.Payload)+32)
+	content = append(content, msg.Type...)
+	content = append(content, '\n')
+	content = strconv.AppendInt(content, msg.Timestamp.UnixMilli(), 10)
+	content = append(content, '\n')
+	content = append(content, msg.Nonce...)
+	content = append(content, '\n')
+	content = append(content, msg.Payload...)
+	return content
+}
+
+// signMessage 计算消息签名（base64）
+func signMessage(msg *Message, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(signingContent(msg))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// commandVerifier 服务器指令签名校验器
+type commandVerifier struct {
+	mode   string
+	window time.Duration
+	seen   map[string]time.Time // nonce -> 消息时间戳
+	mu     sync.Mutex
+}
+
+// newCommandVerifier 创建签名校验器
+func newCommandVerifier(mode string, window time.Duration) *commandVerifier {
+	switch mode {
+	case SigningOff, SigningOptional, SigningRequired:
+	default:
+		mode = SigningOptional
+	}
+	if window <= 0 {
+		window = 5 * time.Minute
+	}
+	return &commandVerifier{
+		mode:   mode,
+		window: window,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// verify 校验指令签名，通过时返回 nil
+func (v *commandVerifier) verify(msg *Message, key []byte, now time.Time) *CommandRejection {
+	if v.mode == SigningOff || !(signedCommandTypes[msg.Type] || credentialMessageTypes[msg.Type]) {
+		return nil
+	}
+
+	if msg.Signature == "" {
+		if v.mode == SigningRequired {
+			return &CommandRejection{Code: RejectSignatureRequired, Message: "设备要求服务器指令必须签名"}
+		}
+		return nil
+	}
+
+	if len(key) == 0 {
+		return &CommandRejection{Code: RejectSignatureInvalid, Message: "设备尚未获得签名密钥"}
+	}
+
+	expected := signMessage(msg, key)
+	if !hmac.Equal([]byte(expected), []byte(msg.Signature)) {
+		return &CommandRejection{Code: RejectSignatureInvalid, Message: "指令签名校验失败"}
+	}
+
+	// 签名正确后再校验时间和 nonce，避免伪造消息污染 nonce 记录
+	skew := now.Sub(msg.Timestamp)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > v.window {
+		return &CommandRejection{Code: RejectTimestampInvalid, Message: "指令时间戳超出允许范围"}
+	}
+
+	if msg.Nonce == "" {
+		return &CommandRejection{Code: RejectSignatureInvalid, Message: "签名指令缺少 nonce"}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// 清理过期的 nonce
+	for nonce, ts := range v.seen {
+		if now.Sub(ts) > v.window {
+			delete(v.seen, nonce)
+		}
+	}
+
+	if _, exists := v.seen[msg.Nonce]; exists {
+		return &CommandRejection{Code: RejectNonceReplayed, Message: "指令 nonce 重复，疑似重放"}
+	}
+	v.seen[msg.Nonce] = msg.Timestamp
+
+	msg.verified = true
+	return nil
+}
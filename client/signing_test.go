@@ -0,0 +1,230 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"maaend-client/config"
+)
+
+func signedTestMessage(t *testing.T, key []byte, msgType, nonce string, ts time.Time) *Message {
+	t.Helper()
+	return signedTestPayload(t, key, msgType, nonce, ts, `{"job_id":"j1"}`)
+}
+
+func signedTestPayload(t *testing.T, key []byte, msgType, nonce string, ts time.Time, payload string) *Message {
+	t.Helper()
+	msg := &Message{
+		Type:      msgType,
+		Payload:   json.RawMessage(payload),
+		Timestamp: ts,
+		Nonce:     nonce,
+	}
+	msg.Signature = signMessage(msg, key)
+
+	// 模拟网络传输后重新解析
+	data, _ := json.Marshal(msg)
+	parsed, err := UnmarshalMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestCommandVerifier(t *testing.T) {
+	now := time.Now()
+	key := deriveSigningKey("secret-123")
+
+	cases := []struct {
+		name string
+		mode string
+		msg  func() *Message
+		want string
+	}{
+		{"签名正确", SigningRequired, func() *Message {
+			return signedTestMessage(t, key, MsgTypeRunTask, "n1", now)
+		}, ""},
+		{"未签名-required", SigningRequired, func() *Message {
+			return &Message{Type: MsgTypeRunTask, Timestamp: now}
+		}, RejectSignatureRequired},
+		{"未签名-optional", SigningOptional, func() *Message {
+			return &Message{Type: MsgTypeRunTask, Timestamp: now}
+		}, ""},
+		{"非指令消息不校验", SigningRequired, func() *Message {
+			return &Message{Type: MsgTypePong, Timestamp: now}
+		}, ""},
+		{"密钥错误", SigningOptional, func() *Message {
+			return signedTestMessage(t, deriveSigningKey("other"), MsgTypeRunTask, "n2", now)
+		}, RejectSignatureInvalid},
+		{"负载被篡改", SigningRequired, func() *Message {
+			msg := signedTestMessage(t, key, MsgTypeRunTask, "n3", now)
+			msg.Payload = json.RawMessage(`{"job_id":"j2"}`)
+			return msg
+		}, RejectSignatureInvalid},
+		{"时间戳过期", SigningRequired, func() *Message {
+			return signedTestMessage(t, key, MsgTypeRunTask, "n4", now.Add(-10*time.Minute))
+		}, RejectTimestampInvalid},
+	}
+
+	for _, tc := range cases {
+		v := newCommandVerifier(tc.mode, 5*time.Minute)
+		got := ""
+		if r := v.verify(tc.msg(), key, now); r != nil {
+			got = r.Code
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCommandVerifierReplay(t *testing.T) {
+	now := time.Now()
+	key := deriveSigningKey("secret-123")
+	v := newCommandVerifier(SigningRequired, 5*time.Minute)
+
+	msg := signedTestMessage(t, key, MsgTypeStopTask, "same-nonce", now)
+	if r := v.verify(msg, key, now); r != nil {
+		t.Fatalf("首次应通过: %v", r)
+	}
+	if r := v.verify(msg, key, now); r == nil || r.Code != RejectNonceReplayed {
+		t.Fatalf("重放应被拒绝, got %v", r)
+	}
+}
+
+func TestGoingAwayAlternateURL(t *testing.T) {
+	now := time.Now()
+	cfg := &config.Config{}
+	cfg.Server.WsURLs = []string{"wss://primary", "wss://backup"}
+	cfg.Server.AllowedRedirectHosts = []string{"edge.example.com", "*.example.net"}
+	cfg.Security.CommandSigning = SigningOptional
+
+	payload := func(url string) string {
+		return `{"reason":"restart","alternate_url":"` + url + `"}`
+	}
+	cases := []struct {
+		name   string
+		signed bool
+		url    string
+		want   string
+	}{
+		{"未签名-已配置", false, "wss://backup", "wss://backup"},
+		{"未签名-允许的主机", false, "wss://edge.example.com/ws", ""},
+		{"不在配置中", true, "wss://attacker", ""},
+		{"签名且已配置", true, "wss://backup", "wss://backup"},
+		{"签名-允许的主机", true, "wss://edge.example.com/ws", "wss://edge.example.com/ws"},
+		{"签名-允许的子域名", true, "wss://a.example.net/ws", "wss://a.example.net/ws"},
+		{"签名-非 TLS", true, "ws://edge.example.com/ws", ""},
+		{"签名-相似主机", true, "wss://evilexample.net/ws", ""},
+	}
+	for i, tc := range cases {
+		c := NewClient(cfg)
+		c.deviceToken = "token-123"
+		c.signingSecret = "secret-123"
+
+		msg := &Message{Type: MsgTypeGoingAway, Payload: json.RawMessage(payload(tc.url)), Timestamp: now}
+		if tc.signed {
+			msg = signedTestPayload(t, deriveSigningKey(c.signingSecret), MsgTypeGoingAway, fmt.Sprintf("n%d", i), now, payload(tc.url))
+		}
+		c.handleMessage(msg)
+		if c.redirectURL != tc.want {
+			t.Errorf("%s: redirectURL = %q, want %q", tc.name, c.redirectURL, tc.want)
+		}
+	}
+}
+
+func TestRotateSigningSecret(t *testing.T) {
+	now := time.Now()
+	cfg := &config.Config{}
+	cfg.Security.CommandSigning = SigningOptional
+
+	newClient := func(secret string) *Client {
+		c := NewClient(cfg)
+		c.deviceID = "dev-1"
+		c.deviceToken = "token-123"
+		c.signingSecret = secret
+		return c
+	}
+	payload := `{"device_token":"token-new","signing_secret":"secret-evil"}`
+	unsigned := &Message{Type: MsgTypeRotateToken, Payload: json.RawMessage(payload), Timestamp: now}
+
+	// optional 模式下未签名的轮换不能替换已有的签名密钥
+	c := newClient("secret-123")
+	c.handleMessage(unsigned)
+	if c.signingSecret != "secret-123" || c.deviceToken != "token-123" {
+		t.Errorf("未签名的轮换不应生效: %q / %q", c.deviceToken, c.signingSecret)
+	}
+
+	c.handleMessage(signedTestPayload(t, deriveSigningKey("secret-123"), MsgTypeRotateToken, "n1", now, payload))
+	if c.signingSecret != "secret-evil" || c.deviceToken != "token-new" {
+		t.Errorf("签名正确的轮换应生效: %q / %q", c.deviceToken, c.signingSecret)
+	}
+
+	// 尚无签名密钥时允许首次下发
+	c = newClient("")
+	c.handleMessage(unsigned)
+	if c.signingSecret != "secret-evil" {
+		t.Errorf("首次下发签名密钥应生效: %q", c.signingSecret)
+	}
+}
+
+func TestCredentialMessages(t *testing.T) {
+	now := time.Now()
+	cfg := &config.Config{}
+	cfg.Security.CommandSigning = SigningRequired
+
+	newClient := func() *Client {
+		c := NewClient(cfg)
+		c.deviceID = "dev-1"
+		c.deviceToken = "token-123"
+		c.signingSecret = "secret-123"
+		return c
+	}
+
+	// 用设备令牌派生密钥签名的指令无效
+	c := newClient()
+	c.handleMessage(signedTestPayload(t, deriveSigningKey(c.deviceToken), MsgTypeAuthFailed, "n1", now, `{"error":"invalid_token"}`))
+	if !c.HasToken() {
+		t.Error("用设备令牌签名的 auth_failed 不应清除凭证")
+	}
+
+	// required 模式下未签名的 auth_failed 不清除凭证
+	c.handleMessage(&Message{Type: MsgTypeAuthFailed, Payload: json.RawMessage(`{"error":"invalid_token"}`), Timestamp: now})
+	if !c.HasToken() {
+		t.Error("未签名的 auth_failed 不应清除凭证")
+	}
+
+	c.handleMessage(signedTestPayload(t, deriveSigningKey(c.signingSecret), MsgTypeAuthFailed, "n2", now, `{"error":"invalid_token"}`))
+	if c.HasToken() || c.signingSecret != "" {
+		t.Error("签名正确的 auth_failed 应清除凭证")
+	}
+
+	registered := &Message{
+		Type:      MsgTypeRegistered,
+		Payload:   json.RawMessage(`{"device_id":"dev-2","device_token":"token-new","signing_secret":"secret-new"}`),
+		Timestamp: now,
+	}
+
+	// 未发送 register 时忽略注册响应
+	c = newClient()
+	c.handleMessage(registered)
+	if c.deviceToken != "token-123" {
+		t.Error("未请求的注册响应不应覆盖凭证")
+	}
+
+	// required 模式下不覆盖已有凭证
+	c.registering.Store(true)
+	c.handleMessage(registered)
+	if c.deviceToken != "token-123" {
+		t.Error("required 模式下注册响应不应覆盖已有凭证")
+	}
+
+	c = NewClient(cfg)
+	c.registering.Store(true)
+	c.handleMessage(registered)
+	if c.deviceToken != "token-new" || c.signingSecret != "secret-new" {
+		t.Errorf("注册后凭证 = %q / %q", c.deviceToken, c.signingSecret)
+	}
+}
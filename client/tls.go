@@ -0,0 +1,105 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"maaend-client/config"
+)
+
+// PinMismatchError 服务器证书公钥指纹与配置不匹配
+type PinMismatchError struct {
+	ServerName string
+	Got        []string // 服务器证书链中各证书的指纹
+}
+
+func (e *PinMismatchError) Error() string {
+	return fmt.Sprintf("服务器 %s 的证书指纹不在 pins 列表中 (证书链指纹: %s)",
+		e.ServerName, strings.Join(e.Got, ", "))
+}
+
+// buildTLSConfig 根据配置构建 TLS 配置
+func buildTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	minVersion, err := parseTLSVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.MinVersion = minVersion
+
+	// 自定义 CA
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 CA 证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA 证书文件中没有有效的 PEM 证书: %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// 客户端证书
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, fmt.Errorf("cert_file 和 key_file 必须同时配置")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	// 证书指纹校验（在常规证书链校验之后执行）
+	if len(cfg.Pins) > 0 {
+		pins := make(map[string]bool, len(cfg.Pins))
+		for _, pin := range cfg.Pins {
+			pins[normalizePin(pin)] = true
+		}
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			got := make([]string, 0, len(cs.PeerCertificates))
+			for _, cert := range cs.PeerCertificates {
+				fingerprint := spkiFingerprint(cert)
+				if pins[fingerprint] {
+					return nil
+				}
+				got = append(got, fingerprint)
+			}
+			return &PinMismatchError{ServerName: cs.ServerName, Got: got}
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// parseTLSVersion 解析 TLS 版本字符串
+func parseTLSVersion(version string) (uint16, error) {
+	switch strings.TrimSpace(version) {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("不支持的最低 TLS 版本: %s (可选: 1.2, 1.3)", version)
+	}
+}
+
+// spkiFingerprint 计算证书公钥（SPKI）的 SHA-256 指纹（base64）
+func spkiFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// normalizePin 去掉 "sha256/" 前缀和空白
+func normalizePin(pin string) string {
+	pin = strings.TrimSpace(pin)
+	return strings.TrimPrefix(pin, "sha256/")
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"maaend-client/config"
+)
+
+func newTLSTestServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return srv, caFile
+}
+
+func dialWithTLS(t *testing.T, srv *httptest.Server, cfg config.TLSConfig) error {
+	t.Helper()
+
+	tlsConfig, err := buildTLSConfig(cfg)
+	if err != nil {
+		t.Fatalf("buildTLSConfig: %v", err)
+	}
+	dialer := websocket.Dialer{TLSClientConfig: tlsConfig}
+	conn, _, err := dialer.Dial("wss"+strings.TrimPrefix(srv.URL, "https"), nil)
+	if err == nil {
+		conn.Close()
+	}
+	return err
+}
+
+func TestTLSPinning(t *testing.T) {
+	srv, caFile := newTLSTestServer(t)
+	pin := "sha256/" + spkiFingerprint(srv.Certificate())
+
+	if err := dialWithTLS(t, srv, config.TLSConfig{CAFile: caFile, Pins: []string{pin}}); err != nil {
+		t.Fatalf("指纹匹配时应连接成功: %v", err)
+	}
+
+	err := dialWithTLS(t, srv, config.TLSConfig{CAFile: caFile, Pins: []string{"AAAA"}})
+	var pinErr *PinMismatchError
+	if !errors.As(err, &pinErr) {
+		t.Fatalf("指纹不匹配时应返回 PinMismatchError, got %v", err)
+	}
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	if _, err := buildTLSConfig(config.TLSConfig{MinVersion: "1.0"}); err == nil {
+		t.Fatal("不支持的 TLS 版本应报错")
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 传输方式
+const (
+	TransportAuto      = "auto"      // 按 URL 协议选择：ws/wss 使用 WebSocket，http/https 使用长轮询
+	TransportWebSocket = "websocket" // WebSocket
+	TransportLongPoll  = "longpoll"  // HTTP 长轮询
+	TransportSSE       = "sse"       // Server-Sent Events 下行 + HTTP POST 上行
+)
+
+// Transport 与服务器之间的消息传输通道
+// 每条消息都是一个完整的 JSON 编码 Message，与具体传输方式无关
+type Transport interface {
+	// ReadMessage 阻塞读取下一条消息；连接正常关闭时返回 io.EOF
+	ReadMessage() ([]byte, error)
+	// WriteMessage 发送一条消息
+	WriteMessage(data []byte) error
+	// Close 关闭连接，可重复调用
+	Close() error
+}
+
+// transportOptions 建立传输连接所需的参数
+type transportOptions struct {
+	URL         string
+	Header      http.Header
+	Timeout     time.Duration
+	TLSConfig   *tls.Config
+	DialContext dialContextFunc // 为 nil 时直连
+}
+
+// HandshakeError 握手阶段服务器返回了非成功的 HTTP 响应
+type HandshakeError struct {
+	StatusCode int
+	Header     http.Header
+	Err        error
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("HTTP %d: %v", e.StatusCode, e.Err)
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// resolveTransport 确定传输方式，并将 URL 协议转换为该方式使用的协议
+func resolveTransport(setting, rawURL string) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("服务器地址无效: %w", err)
+	}
+
+	kind := strings.ToLower(strings.TrimSpace(setting))
+	if kind == "" || kind == TransportAuto {
+		switch u.Scheme {
+		case "ws", "wss":
+			kind = TransportWebSocket
+		case "http", "https":
+			kind = TransportLongPoll
+		default:
+			return "", "", fmt.Errorf("无法根据协议 %q 选择传输方式", u.Scheme)
+		}
+	}
+
+	secure := u.Scheme == "wss" || u.Scheme == "https"
+	switch kind {
+	case TransportWebSocket:
+		u.Scheme = "ws"
+		if secure {
+			u.Scheme = "wss"
+		}
+	case TransportLongPoll, TransportSSE:
+		u.Scheme = "http"
+		if secure {
+			u.Scheme = "https"
+		}
+	default:
+		return "", "", fmt.Errorf("不支持的传输方式: %s (可选: auto, websocket, longpoll, sse)", setting)
+	}
+
+	return kind, u.String(), nil
+}
+
+// dialTransport 按传输方式建立连接
+func dialTransport(ctx context.Context, kind string, opts transportOptions) (Transport, error) {
+	switch kind {
+	case TransportWebSocket:
+		return dialWebSocket(ctx, opts)
+	case TransportLongPoll, TransportSSE:
+		return dialHTTPTransport(ctx, kind, opts)
+	default:
+		return nil, fmt.Errorf("不支持的传输方式: %s", kind)
+	}
+}
+
+// netDialer 获取底层拨号函数（未配置代理时直连）
+func (o *transportOptions) netDialer() dialContextFunc {
+	if o.DialContext != nil {
+		return o.DialContext
+	}
+	d := &net.Dialer{Timeout: o.Timeout}
+	return d.DialContext
+}
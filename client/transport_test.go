@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHTTPTransportServer 启动一个实现长轮询/SSE 协议的测试服务器，
+// 将收到的上行消息原样作为下行消息返回
+func newHTTPTransportServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	queue := make(chan []byte, 16)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /ws/connect", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(connectResponse{SessionID: "s1"})
+	})
+	mux.HandleFunc("POST /ws/send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(sessionHeader) != "s1" {
+			http.Error(w, "bad session", http.StatusNotFound)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		queue <- data
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("GET /ws/poll", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case msg := <-queue:
+			w.Write([]byte("[" + string(msg) + "]"))
+		case <-time.After(200 * time.Millisecond):
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	mux.HandleFunc("GET /ws/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		fmt.Fprint(w, ": keepalive\n\n")
+		flusher.Flush()
+		for {
+			select {
+			case msg := <-queue:
+				fmt.Fprintf(w, "data: %s\n\n", msg)
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("POST /ws/close", func(w http.ResponseWriter, r *http.Request) {})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPTransportEcho(t *testing.T) {
+	for _, setting := range []string{TransportLongPoll, TransportSSE} {
+		t.Run(setting, func(t *testing.T) {
+			srv := newHTTPTransportServer(t)
+			kind, dialURL, err := resolveTransport(setting, "ws"+srv.URL[len("http"):]+"/ws")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tr, err := dialTransport(t.Context(), kind, transportOptions{URL: dialURL, Timeout: time.Second})
+			if err != nil {
+				t.Fatalf("建立会话失败: %v", err)
+			}
+			defer tr.Close()
+
+			sent, _ := MarshalMessage(MsgTypePing, nil)
+			if err := tr.WriteMessage(sent); err != nil {
+				t.Fatalf("发送失败: %v", err)
+			}
+
+			got, err := tr.ReadMessage()
+			if err != nil {
+				t.Fatalf("接收失败: %v", err)
+			}
+			msg, err := UnmarshalMessage(got)
+			if err != nil || msg.Type != MsgTypePing {
+				t.Fatalf("回显消息不一致: %s, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestHTTPTransportPollRetry(t *testing.T) {
+	old := pollRetryDelay
+	pollRetryDelay = 10 * time.Millisecond
+	defer func() { pollRetryDelay = old }()
+
+	// newServer 前 failures 次轮询返回 status，之后返回一条消息
+	newServer := func(failures int32, status int) *httptest.Server {
+		var polls atomic.Int32
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /ws/connect", func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(connectResponse{SessionID: "s1"})
+		})
+		mux.HandleFunc("GET /ws/poll", func(w http.ResponseWriter, r *http.Request) {
+			if polls.Add(1) <= failures {
+				w.WriteHeader(status)
+				return
+			}
+			w.Write([]byte(`[{"type":"pong"}]`))
+		})
+		mux.HandleFunc("POST /ws/close", func(w http.ResponseWriter, r *http.Request) {})
+		srv := httptest.NewServer(mux)
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	cases := []struct {
+		name     string
+		failures int32
+		status   int
+		wantErr  bool
+	}{
+		{"短暂失败后恢复", pollRetries, http.StatusServiceUnavailable, false},
+		{"连续失败超过重试次数", pollRetries + 1, http.StatusServiceUnavailable, true},
+		{"会话失效不重试", 1, http.StatusNotFound, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newServer(tc.failures, tc.status)
+			tr, err := dialTransport(t.Context(), TransportLongPoll, transportOptions{URL: srv.URL + "/ws", Timeout: time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tr.Close()
+
+			_, err = tr.ReadMessage()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ReadMessage() err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPTransportDrainsBeforeError(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	tr := &httpTransport{
+		incoming: make(chan []byte, 64),
+		errCh:    make(chan error, 1),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	for i := 0; i < 10; i++ {
+		tr.incoming <- []byte(fmt.Sprint(i))
+	}
+	tr.fail(io.ErrUnexpectedEOF)
+
+	for i := 0; i < 10; i++ {
+		got, err := tr.ReadMessage()
+		if err != nil || string(got) != fmt.Sprint(i) {
+			t.Fatalf("第 %d 条消息: %q, %v", i, got, err)
+		}
+	}
+	if _, err := tr.ReadMessage(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("消息读完后应返回错误, got %v", err)
+	}
+}
+
+func TestResolveTransport(t *testing.T) {
+	cases := []struct {
+		setting, url string
+		kind, out    string
+	}{
+		{"auto", "wss://a/ws", TransportWebSocket, "wss://a/ws"},
+		{"", "https://a/ws", TransportLongPoll, "https://a/ws"},
+		{"sse", "wss://a/ws", TransportSSE, "https://a/ws"},
+		{"websocket", "http://a/ws", TransportWebSocket, "ws://a/ws"},
+	}
+	for _, tc := range cases {
+		kind, out, err := resolveTransport(tc.setting, tc.url)
+		if err != nil || kind != tc.kind || out != tc.out {
+			t.Errorf("resolveTransport(%q, %q) = %s, %s, %v; want %s, %s", tc.setting, tc.url, kind, out, err, tc.kind, tc.out)
+		}
+	}
+	if _, _, err := resolveTransport("carrier-pigeon", "wss://a"); err == nil {
+		t.Error("未知传输方式应报错")
+	}
+}
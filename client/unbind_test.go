@@ -0,0 +1,58 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"maaend-client/config"
+)
+
+func TestRequestUnbind(t *testing.T) {
+	cfg := &config.Config{}
+	newClient := func() *Client {
+		c := NewClient(cfg)
+		c.deviceID = "dev-1"
+		c.deviceToken = "token-123"
+		return c
+	}
+	// reply 模拟服务器：读取 unbind_device 后回复确认
+	reply := func(c *Client, payload string) {
+		go func() {
+			data := <-c.sendCh
+			msg, err := UnmarshalMessage(data)
+			if err != nil || msg.Type != MsgTypeUnbindDevice {
+				t.Errorf("应先发送 unbind_device: %s", data)
+				return
+			}
+			c.handleMessage(&Message{Type: MsgTypeDeviceUnbound, Payload: json.RawMessage(payload), Timestamp: time.Now()})
+		}()
+	}
+
+	c := newClient()
+	reply(c, `{"success":true}`)
+	if err := c.RequestUnbind("test"); err != nil || c.HasToken() {
+		t.Fatalf("收到确认后应清除凭证: %v", err)
+	}
+
+	c = newClient()
+	reply(c, `{"success":false,"error":"busy"}`)
+	if err := c.RequestUnbind("test"); err == nil || !c.HasToken() {
+		t.Fatal("服务器拒绝时应保留凭证")
+	}
+
+	// 未收到确认（消息未送达或连接断开）时保留凭证
+	old := unbindAckTimeout
+	unbindAckTimeout = 50 * time.Millisecond
+	defer func() { unbindAckTimeout = old }()
+	c = newClient()
+	if err := c.RequestUnbind("test"); err == nil || !c.HasToken() {
+		t.Fatal("超时未确认时应保留凭证")
+	}
+
+	// 未请求的确认被忽略
+	c.handleMessage(&Message{Type: MsgTypeDeviceUnbound, Payload: json.RawMessage(`{"success":true}`), Timestamp: time.Now()})
+	if !c.HasToken() {
+		t.Fatal("未请求的解绑确认不应清除凭证")
+	}
+}
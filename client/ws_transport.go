@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsTransport WebSocket 传输
+type wsTransport struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// dialWebSocket 建立 WebSocket 连接
+func dialWebSocket(ctx context.Context, opts transportOptions) (Transport, error) {
+	dialer := websocket.Dialer{
+		HandshakeTimeout: opts.Timeout,
+		TLSClientConfig:  opts.TLSConfig,
+		NetDialContext:   opts.DialContext,
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, opts.URL, opts.Header)
+	if err != nil {
+		if resp != nil {
+			return nil, &HandshakeError{StatusCode: resp.StatusCode, Header: resp.Header, Err: err}
+		}
+		return nil, err
+	}
+
+	return &wsTransport{conn: conn}, nil
+}
+
+// ReadMessage 读取消息
+func (t *wsTransport) ReadMessage() ([]byte, error) {
+	_, message, err := t.conn.ReadMessage()
+	if err != nil {
+		if !websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if _, ok := err.(*websocket.CloseError); ok {
+				return nil, io.EOF
+			}
+		}
+		return nil, err
+	}
+	return message, nil
+}
+
+// WriteMessage 发送消息
+func (t *wsTransport) WriteMessage(data []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return t.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Close 关闭连接
+func (t *wsTransport) Close() error {
+	return t.conn.Close()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"maaend-client/audit"
+	"maaend-client/config"
+)
+
+// runAuditCommand 查看审计日志
+func runAuditCommand(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	cfgPath := fs.String("c", "", "配置文件路径")
+	limit := fs.Int("n", 50, "显示最近的记录条数（0 表示全部）")
+	msgType := fs.String("type", "", "按指令类型过滤（如 run_task）")
+	event := fs.String("event", "", "按事件过滤: received, rejected, outcome")
+	ref := fs.String("ref", "", "按任务 ID / 请求 ID 过滤")
+	since := fs.Duration("since", 0, "只显示最近一段时间内的记录（如 24h）")
+	asJSON := fs.Bool("json", false, "以 JSON Lines 输出")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return exitFailure
+	}
+	if cfg.Logging.AuditFile == "" {
+		fmt.Fprintln(os.Stderr, "未启用审计日志（logging.audit_file 为空）")
+		return exitFailure
+	}
+
+	path := config.ResolvePath(cfg.Logging.AuditFile)
+	entries, err := audit.ReadAll(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取审计日志失败: %v\n", err)
+		return exitFailure
+	}
+
+	var cutoff time.Time
+	if *since > 0 {
+		cutoff = time.Now().Add(-*since)
+	}
+
+	filtered := entries[:0]
+	for _, e := range entries {
+		if *msgType != "" && e.Type != *msgType {
+			continue
+		}
+		if *event != "" && e.Event != *event {
+			continue
+		}
+		if *ref != "" && e.Ref != *ref {
+			continue
+		}
+		if !cutoff.IsZero() && e.Time.Before(cutoff) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	if *limit > 0 && len(filtered) > *limit {
+		filtered = filtered[len(filtered)-*limit:]
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for i := range filtered {
+			enc.Encode(&filtered[i])
+		}
+		return exitOK
+	}
+
+	if len(filtered) == 0 {
+		fmt.Println("没有匹配的审计记录")
+		return exitOK
+	}
+	for i := range filtered {
+		fmt.Println(formatAuditEntry(&filtered[i]))
+	}
+	return exitOK
+}
+
+// formatAuditEntry 格式化一条审计记录
+func formatAuditEntry(e *audit.Entry) string {
+	parts := []string{
+		e.Time.Local().Format("2006-01-02 15:04:05"),
+		fmt.Sprintf("%-8s", e.Event),
+		fmt.Sprintf("%-18s", e.Type),
+	}
+	if e.Ref != "" {
+		parts = append(parts, "ref="+e.Ref)
+	}
+
+	switch e.Event {
+	case audit.EventReceived:
+		if e.Signed {
+			parts = append(parts, "signed")
+		}
+		if len(e.Payload) > 0 {
+			parts = append(parts, string(e.Payload))
+		}
+	case audit.EventRejected:
+		parts = append(parts, "code="+e.Code)
+	case audit.EventOutcome:
+		parts = append(parts, "result="+e.Result)
+		if e.DurationMs > 0 {
+			parts = append(parts, fmt.Sprintf("duration=%dms", e.DurationMs))
+		}
+	}
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	}
+
+	return strings.Join(parts, "  ")
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"maaend-client/client"
+	"maaend-client/config"
+	"maaend-client/core"
+	"maaend-client/maa"
+)
+
+// 检查结果
+const (
+	checkPass = "通过"
+	checkWarn = "警告"
+	checkFail = "失败"
+	checkSkip = "跳过"
+)
+
+// doctorReport 诊断报告
+type doctorReport struct {
+	failed int
+	warned int
+}
+
+// add 输出一项检查结果，details 逐行缩进显示
+func (r *doctorReport) add(status, name, summary string, details ...string) {
+	switch status {
+	case checkFail:
+		r.failed++
+	case checkWarn:
+		r.warned++
+	}
+	fmt.Printf("[%s] %s: %s\n", status, name, summary)
+	for _, d := range details {
+		fmt.Printf("       %s\n", d)
+	}
+}
+
+// runDoctorCommand 检查运行环境并输出诊断报告
+func runDoctorCommand(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	cfgPath := fs.String("c", "", "配置文件路径")
+	maaEnd := fs.String("maaend", "", "MaaEnd 安装路径")
+	offline := fs.Bool("offline", false, "跳过服务器连通性检查")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	// 诊断过程中的内部日志会打乱报告，统一丢弃
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	report := &doctorReport{}
+	cfg := checkConfig(report, *cfgPath)
+	if cfg == nil {
+		return finishDoctor(report)
+	}
+	if *maaEnd != "" {
+		cfg.MaaEnd.Path = *maaEnd
+	}
+
+	if pi := checkInterface(report, cfg); pi != nil {
+		checkWin32Regex(report, cfg, pi)
+		checkAgent(report, pi)
+		checkDevices(report, cfg, pi)
+	}
+
+	if *offline {
+		report.add(checkSkip, "服务器连通性", "已指定 -offline")
+	} else {
+		checkServers(report, cfg)
+	}
+
+	return finishDoctor(report)
+}
+
+// finishDoctor 输出汇总并返回退出码
+func finishDoctor(r *doctorReport) int {
+	fmt.Printf("\n诊断完成: %d 项失败, %d 项警告\n", r.failed, r.warned)
+	if r.failed > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// checkConfig 检查配置文件能否加载，且保存后重新读取保持一致
+func checkConfig(r *doctorReport, path string) *config.Config {
+	cfg, err := config.Load(path)
+	if err != nil {
+		r.add(checkFail, "配置文件", err.Error())
+		return nil
+	}
+	if _, err := os.Stat(config.FilePath()); err != nil {
+		r.add(checkWarn, "配置文件", fmt.Sprintf("%s 不存在，使用默认配置", config.FilePath()))
+	} else {
+		r.add(checkPass, "配置文件", config.FilePath())
+	}
+
+	if err := config.VerifyRoundTrip(); err != nil {
+		r.add(checkFail, "配置保存", err.Error(), "客户端修改配置后（如绑定设备）将无法正确读取")
+	} else {
+		r.add(checkPass, "配置保存", "保存后重新读取一致")
+	}
+	return cfg
+}
+
+// checkInterface 检查 MaaEnd 目录与 interface.json（含导入文件）
+func checkInterface(r *doctorReport, cfg *config.Config) *core.ProjectInterface {
+	if cfg.MaaEnd.Path == "" {
+		r.add(checkFail, "MaaEnd 目录", "未找到 MaaEnd 安装目录", "请在配置文件中设置 maaend.path 或使用 -maaend 参数")
+		return nil
+	}
+	if !config.IsMaaEndDir(cfg.MaaEnd.Path) {
+		r.add(checkFail, "MaaEnd 目录", cfg.MaaEnd.Path, "目录中缺少 interface.json 或 maafw 目录")
+		return nil
+	}
+	r.add(checkPass, "MaaEnd 目录", cfg.MaaEnd.Path)
+
+	pi, err := core.LoadInterface(cfg.MaaEnd.Path)
+	if err != nil {
+		r.add(checkFail, "interface.json", err.Error())
+		return nil
+	}
+
+	summary := fmt.Sprintf("%s v%s: %d 个控制器, %d 个资源, %d 个任务, %d 个选项",
+		pi.Name, pi.Version, len(pi.Controllers), len(pi.Resources), len(pi.Tasks), len(pi.Options))
+	r.add(checkPass, "interface.json", summary)
+
+	var errs []string
+	warnings := 0
+	for _, d := range pi.Validate() {
+		if d.Severity == core.SeverityError {
+			errs = append(errs, d.String())
+		} else {
+			warnings++
+		}
+	}
+	switch {
+	case len(errs) > 0:
+		r.add(checkFail, "interface.json 校验", fmt.Sprintf("%d 个错误, %d 个警告", len(errs), warnings), errs...)
+	case warnings > 0:
+		r.add(checkWarn, "interface.json 校验", fmt.Sprintf("%d 个警告", warnings), "运行 lint 子命令查看详情")
+	default:
+		r.add(checkPass, "interface.json 校验", "没有发现问题")
+	}
+	return pi
+}
+
+// checkWin32Regex 检查 Win32 控制器和配置覆盖中的窗口匹配正则
+func checkWin32Regex(r *doctorReport, cfg *config.Config, pi *core.ProjectInterface) {
+	type pattern struct{ source, value string }
+	var patterns []pattern
+	for _, ctrl := range pi.Controllers {
+		if ctrl.Win32 == nil {
+			continue
+		}
+		patterns = append(patterns,
+			pattern{ctrl.Name + ".class_regex", ctrl.Win32.ClassRegex},
+			pattern{ctrl.Name + ".window_regex", ctrl.Win32.WindowRegex})
+	}
+	patterns = append(patterns,
+		pattern{"maaend.win32_class_regex", cfg.MaaEnd.Win32ClassRegex},
+		pattern{"maaend.win32_window_regex", cfg.MaaEnd.Win32WindowRegex})
+
+	var errs []string
+	checked := 0
+	for _, p := range patterns {
+		if p.value == "" {
+			continue
+		}
+		checked++
+		if _, err := regexp.Compile(p.value); err != nil {
+			errs = append(errs, fmt.Sprintf("%s %q: %v", p.source, p.value, err))
+		}
+	}
+
+	switch {
+	case len(errs) > 0:
+		// 语法错误时客户端会回退到包含匹配，通常无法匹配到窗口
+		r.add(checkFail, "窗口匹配规则", fmt.Sprintf("%d 条正则表达式无效", len(errs)), errs...)
+	case checked == 0:
+		r.add(checkSkip, "窗口匹配规则", "没有 Win32 控制器")
+	default:
+		r.add(checkPass, "窗口匹配规则", fmt.Sprintf("%d 条正则表达式有效", checked))
+	}
+}
+
+// checkAgent 检查 Agent 可执行文件
+func checkAgent(r *doctorReport, pi *core.ProjectInterface) {
+	agentExec := pi.GetAgentExec()
+	if agentExec == "" {
+		r.add(checkSkip, "Agent", "interface.json 未配置 agent")
+		return
+	}
+	info, err := os.Stat(agentExec)
+	switch {
+	case err != nil:
+		r.add(checkFail, "Agent", fmt.Sprintf("可执行文件不存在: %s", agentExec), "自定义识别器和动作将不可用，请重新安装 MaaEnd")
+	case info.IsDir():
+		r.add(checkFail, "Agent", fmt.Sprintf("路径是目录: %s", agentExec))
+	default:
+		r.add(checkPass, "Agent", agentExec)
+	}
+}
+
+// checkDevices 初始化 MaaFramework 并列出可用的窗口和 ADB 设备
+func checkDevices(r *doctorReport, cfg *config.Config, pi *core.ProjectInterface) {
+	wrapper := maa.NewWrapper(cfg.MaaEnd.Path)
+	if err := wrapper.Init(); err != nil {
+		r.add(checkFail, "MaaFramework", err.Error(), fmt.Sprintf("库目录: %s", pi.GetMaaFWPath()))
+		return
+	}
+	defer wrapper.Cleanup()
+	r.add(checkPass, "MaaFramework", pi.GetMaaFWPath())
+
+	for _, ctrl := range pi.Controllers {
+		name := fmt.Sprintf("控制器 %s", ctrl.Name)
+		switch ctrl.Type {
+		case "Win32":
+			windows, err := wrapper.FindWindows(ctrl.Name)
+			if err != nil {
+				r.add(checkFail, name, err.Error())
+				continue
+			}
+			var matched []string
+			for _, win := range windows {
+				if win.Matched {
+					matched = append(matched, fmt.Sprintf("class=%q window=%q", win.ClassName, win.WindowName))
+				}
+			}
+			if len(matched) == 0 {
+				r.add(checkWarn, name, fmt.Sprintf("共 %d 个窗口，没有匹配的游戏窗口", len(windows)),
+					"请确认游戏已启动；窗口标题不同时可设置 maaend.win32_window_regex")
+			} else {
+				r.add(checkPass, name, fmt.Sprintf("匹配到 %d 个窗口", len(matched)), matched...)
+			}
+		case "Adb":
+			devices, err := wrapper.FindAdbDevices()
+			if err != nil {
+				r.add(checkFail, name, err.Error())
+				continue
+			}
+			if len(devices) == 0 {
+				r.add(checkWarn, name, "未找到 ADB 设备", "请确认模拟器已启动并开启 ADB 调试")
+				continue
+			}
+			details := make([]string, 0, len(devices))
+			for _, d := range devices {
+				details = append(details, fmt.Sprintf("%s %s (%s)", d.Name, d.Address, d.AdbPath))
+			}
+			r.add(checkPass, name, fmt.Sprintf("找到 %d 个设备", len(devices)), details...)
+		default:
+			r.add(checkWarn, name, fmt.Sprintf("不支持的控制器类型: %s", ctrl.Type))
+		}
+	}
+}
+
+// checkServers 测试服务器地址的连通性（建立传输连接后立即断开，不进行认证）
+func checkServers(r *doctorReport, cfg *config.Config) {
+	endpoints := cfg.Server.Endpoints()
+	if len(endpoints) == 0 {
+		r.add(checkFail, "服务器连通性", "未配置服务器地址")
+		return
+	}
+
+	c := client.NewClient(cfg)
+	for _, url := range endpoints {
+		elapsed, err := c.Probe(context.Background(), url)
+		if err != nil {
+			r.add(checkFail, "服务器 "+url, err.Error())
+			continue
+		}
+		r.add(checkPass, "服务器 "+url, fmt.Sprintf("连接成功，耗时 %s", elapsed.Round(time.Millisecond)))
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"maaend-client/client"
+	"maaend-client/config"
+	"maaend-client/core"
+)
+
+// runExplainCommand 显示任务选项的解析过程（不执行任务）
+func runExplainCommand(args []string) int {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	cfgPath := fs.String("c", "", "配置文件路径")
+	maaEnd := fs.String("maaend", "", "MaaEnd 安装路径")
+	task := fs.String("task", "", "任务名称")
+	var options stringList
+	fs.Var(&options, "option", "选项 key=value，可重复指定；value 为合法 JSON 时按 JSON 解析")
+	asJSON := fs.Bool("json", false, "以 JSON 输出")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *task == "" {
+		fmt.Fprintln(os.Stderr, "需要指定 -task")
+		fs.Usage()
+		return exitUsage
+	}
+
+	userOptions, err := parseOptionArgs(options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return exitFailure
+	}
+	if *maaEnd != "" {
+		cfg.MaaEnd.Path = *maaEnd
+	}
+	if cfg.MaaEnd.Path == "" {
+		fmt.Fprintln(os.Stderr, "未找到 MaaEnd 安装目录，请使用 -maaend 参数指定")
+		return exitUsage
+	}
+	pi, err := core.LoadInterface(cfg.MaaEnd.Path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	explanation, resolveErr := core.NewOptionResolver(pi).ExplainTaskOptions(*task, userOptions)
+	if *asJSON {
+		result := &client.OptionsExplainedPayload{
+			Task:     *task,
+			Override: explanation.Override,
+			Trace:    explanation.Trace,
+		}
+		if resolveErr != nil {
+			result.Error = resolveErr.Error()
+		}
+		if code := printJSON(result); code != exitOK {
+			return code
+		}
+	} else {
+		printTrace(explanation.Trace)
+		if resolveErr == nil {
+			data, _ := json.MarshalIndent(explanation.Override, "", "  ")
+			fmt.Printf("\n合并后的 pipeline_override:\n%s\n", data)
+		}
+	}
+
+	if resolveErr != nil {
+		fmt.Fprintf(os.Stderr, "解析失败: %v\n", resolveErr)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// caseReasonText case 选择原因的显示文本
+var caseReasonText = map[string]string{
+	client.CaseReasonUser:        "用户指定",
+	client.CaseReasonDefaultCase: "default_case",
+	client.CaseReasonDefault:     "default",
+	client.CaseReasonFirst:       "第一个 case",
+}
+
+// printTrace 以缩进列表输出追踪步骤
+func printTrace(trace []client.OptionTraceStep) {
+	for i, step := range trace {
+		indent := strings.Repeat("  ", step.Depth)
+		switch step.Step {
+		case client.TraceStepTask:
+			fmt.Printf("#%d %s任务 pipeline_override\n", i, indent)
+		case client.TraceStepCase:
+			fmt.Printf("#%d %s%s = %s (%s)\n", i, indent, step.Option, step.Case, caseReasonText[step.Reason])
+		case client.TraceStepInput:
+			fmt.Printf("#%d %s%s 输入: %s\n", i, indent, step.Option, formatInputs(step.Inputs))
+		case client.TraceStepSkipped:
+			name := step.Option
+			if step.Case != "" {
+				name += " = " + step.Case
+			}
+			fmt.Printf("#%d %s%s 已跳过: %s\n", i, indent, name, step.Message)
+		}
+
+		pad := strings.Repeat(" ", len(fmt.Sprint(i))+2) + indent
+		if len(step.Keys) > 0 {
+			fmt.Printf("%s  写入: %s\n", pad, strings.Join(step.Keys, ", "))
+		}
+		for _, o := range step.Overwritten {
+			prev, _ := json.Marshal(o.Previous)
+			val, _ := json.Marshal(o.Value)
+			fmt.Printf("%s  覆盖: %s %s -> %s (原值来自 #%d)\n", pad, o.Key, prev, val, o.PreviousStep)
+		}
+	}
+}
+
+// formatInputs 格式化输入值
+func formatInputs(inputs map[string]interface{}) string {
+	if len(inputs) == 0 {
+		return "(无)"
+	}
+	data, _ := json.Marshal(inputs)
+	return string(data)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"maaend-client/config"
+	"maaend-client/core"
+)
+
+// runLintCommand 校验 interface.json 及其导入文件
+func runLintCommand(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	cfgPath := fs.String("c", "", "配置文件路径")
+	maaEnd := fs.String("maaend", "", "MaaEnd 安装路径")
+	errorsOnly := fs.Bool("errors", false, "只显示错误")
+	asJSON := fs.Bool("json", false, "以 JSON Lines 输出")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return exitFailure
+	}
+	if *maaEnd != "" {
+		cfg.MaaEnd.Path = *maaEnd
+	}
+	if cfg.MaaEnd.Path == "" {
+		fmt.Fprintln(os.Stderr, "未找到 MaaEnd 安装目录，请使用 -maaend 参数指定")
+		return exitUsage
+	}
+
+	pi, err := core.LoadInterface(cfg.MaaEnd.Path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	diags := pi.Validate()
+	enc := json.NewEncoder(os.Stdout)
+	errCount, warnCount := 0, 0
+	for _, d := range diags {
+		if d.Severity == core.SeverityError {
+			errCount++
+		} else {
+			warnCount++
+			if *errorsOnly {
+				continue
+			}
+		}
+		if *asJSON {
+			enc.Encode(&d)
+		} else {
+			fmt.Println(d)
+		}
+	}
+
+	if !*asJSON {
+		fmt.Printf("\n%d 个错误, %d 个警告\n", errCount, warnCount)
+	}
+	if errCount > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
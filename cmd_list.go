@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"maaend-client/config"
+	"maaend-client/core"
+)
+
+// listTopics list 子命令支持的类别
+var listTopics = []string{"tasks", "controllers", "resources", "options"}
+
+// interfaceFlags list/describe 共用参数
+type interfaceFlags struct {
+	cfgPath *string
+	maaEnd  *string
+	lang    *string
+	asJSON  *bool
+}
+
+// newInterfaceFlagSet 创建 list/describe 的参数集
+func newInterfaceFlagSet(name string) (*flag.FlagSet, *interfaceFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	return fs, &interfaceFlags{
+		cfgPath: fs.String("c", "", "配置文件路径"),
+		maaEnd:  fs.String("maaend", "", "MaaEnd 安装路径"),
+		lang:    fs.String("lang", "", "显示语言（如 zh_cn、en_us，默认使用配置的 maaend.language）"),
+		asJSON:  fs.Bool("json", false, "以 JSON 输出"),
+	}
+}
+
+// loadInterface 按配置加载 interface.json（不初始化 MaaFramework）
+func (f *interfaceFlags) loadInterface() (*core.ProjectInterface, error) {
+	cfg, err := config.Load(*f.cfgPath)
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	if *f.maaEnd != "" {
+		cfg.MaaEnd.Path = *f.maaEnd
+	}
+	if cfg.MaaEnd.Path == "" {
+		return nil, fmt.Errorf("未找到 MaaEnd 安装目录，请使用 -maaend 参数指定")
+	}
+	pi, err := core.LoadInterface(cfg.MaaEnd.Path)
+	if err != nil {
+		return nil, err
+	}
+	pi.SetLanguageFallback(cfg.MaaEnd.LanguageFallback)
+	if *f.lang == "" {
+		*f.lang = cfg.MaaEnd.Language
+	}
+	for _, d := range pi.Validate() {
+		if d.Severity == core.SeverityError {
+			fmt.Fprintln(os.Stderr, d)
+		}
+	}
+	return pi, nil
+}
+
+// taskSummary 任务概要
+type taskSummary struct {
+	Name         string   `json:"name"`
+	Label        string   `json:"label"`
+	DefaultCheck bool     `json:"default_check"`
+	Controllers  []string `json:"controllers,omitempty"`
+	Resources    []string `json:"resources,omitempty"`
+	Options      []string `json:"options,omitempty"`
+}
+
+// controllerSummary 控制器概要
+type controllerSummary struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+}
+
+// resourceSummary 资源概要
+type resourceSummary struct {
+	Name string   `json:"name"`
+	Path []string `json:"path"`
+}
+
+// runListCommand 列出 interface.json 中的任务、控制器、资源或选项
+func runListCommand(args []string) int {
+	fs, f := newInterfaceFlagSet("list")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s list [参数] <%s>\n", os.Args[0], strings.Join(listTopics, "|"))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 || !containsString(listTopics, fs.Arg(0)) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	pi, err := f.loadInterface()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	builder := core.NewCapabilitiesBuilder(pi, *f.lang)
+
+	var result interface{}
+	switch fs.Arg(0) {
+	case "tasks":
+		tasks := make([]taskSummary, 0, len(pi.Tasks))
+		for _, task := range pi.Tasks {
+			tasks = append(tasks, taskSummary{
+				Name:         task.Name,
+				Label:        pi.GetI18nString(task.Label, *f.lang),
+				DefaultCheck: task.DefaultCheck,
+				Controllers:  task.Controller,
+				Resources:    task.Resource,
+				Options:      task.Option,
+			})
+		}
+		result = tasks
+	case "controllers":
+		controllers := make([]controllerSummary, 0, len(pi.Controllers))
+		for _, ctrl := range pi.Controllers {
+			controllers = append(controllers, controllerSummary{
+				Name:  ctrl.Name,
+				Label: pi.GetI18nString(ctrl.Label, *f.lang),
+				Type:  ctrl.Type,
+			})
+		}
+		result = controllers
+	case "resources":
+		resources := make([]resourceSummary, 0, len(pi.Resources))
+		for _, res := range pi.Resources {
+			resources = append(resources, resourceSummary{Name: res.Name, Path: res.Path})
+		}
+		result = resources
+	case "options":
+		result = builder.ListOptions()
+	}
+
+	if *f.asJSON {
+		return printJSON(result)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	switch v := result.(type) {
+	case []taskSummary:
+		fmt.Fprintln(w, "名称\t标签\t默认\t控制器\t资源\t选项")
+		for _, t := range v {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", t.Name, t.Label, yesNo(t.DefaultCheck),
+				joinOrAll(t.Controllers), joinOrAll(t.Resources), strings.Join(t.Options, ", "))
+		}
+	case []controllerSummary:
+		fmt.Fprintln(w, "名称\t标签\t类型")
+		for _, c := range v {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.Label, c.Type)
+		}
+	case []resourceSummary:
+		fmt.Fprintln(w, "名称\t路径")
+		for _, r := range v {
+			fmt.Fprintf(w, "%s\t%s\n", r.Name, strings.Join(r.Path, ", "))
+		}
+	case []core.OptionSummary:
+		fmt.Fprintln(w, "名称\t类型\t标签\t使用任务")
+		for _, o := range v {
+			usedBy := strings.Join(o.UsedBy, ", ")
+			if usedBy == "" {
+				usedBy = "(嵌套)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", o.Name, o.Type, o.Label, usedBy)
+		}
+	}
+	w.Flush()
+	return exitOK
+}
+
+// runDescribeCommand 显示任务或选项详情
+func runDescribeCommand(args []string) int {
+	fs, f := newInterfaceFlagSet("describe")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s describe [参数] <task|option> <名称>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 2 || (fs.Arg(0) != "task" && fs.Arg(0) != "option") {
+		fs.Usage()
+		return exitUsage
+	}
+
+	pi, err := f.loadInterface()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	builder := core.NewCapabilitiesBuilder(pi, *f.lang)
+
+	if fs.Arg(0) == "option" {
+		detail, err := builder.DescribeOption(fs.Arg(1))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		if *f.asJSON {
+			return printJSON(detail)
+		}
+		printOptionDetail(*detail, "")
+		return exitOK
+	}
+
+	detail, err := builder.DescribeTask(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if *f.asJSON {
+		return printJSON(detail)
+	}
+
+	fmt.Printf("任务: %s (%s)\n", detail.Name, detail.Label)
+	if detail.Description != "" {
+		fmt.Printf("说明: %s\n", detail.Description)
+	}
+	fmt.Printf("入口: %s\n", detail.Entry)
+	fmt.Printf("默认选中: %s\n", yesNo(detail.DefaultCheck))
+	fmt.Printf("控制器: %s\n", joinOrAll(detail.Controllers))
+	fmt.Printf("资源: %s\n", joinOrAll(detail.Resources))
+	if len(detail.Options) == 0 {
+		fmt.Println("选项: 无")
+		return exitOK
+	}
+	fmt.Println("选项:")
+	for _, opt := range detail.Options {
+		printOptionDetail(opt, "  ")
+	}
+	return exitOK
+}
+
+// printOptionDetail 以树形输出选项详情
+func printOptionDetail(opt core.OptionDetail, indent string) {
+	if opt.Recursive {
+		fmt.Printf("%s%s (%s) [循环引用，已省略]\n", indent, opt.Name, opt.Label)
+		return
+	}
+	fmt.Printf("%s%s [%s] %s\n", indent, opt.Name, opt.Type, opt.Label)
+	if opt.Description != "" {
+		fmt.Printf("%s  说明: %s\n", indent, opt.Description)
+	}
+	for _, c := range opt.Cases {
+		marker := " "
+		if c.Name == opt.DefaultCase {
+			marker = "*"
+		}
+		fmt.Printf("%s  %s %s (%s)\n", indent, marker, c.Name, c.Label)
+		for _, nested := range c.Options {
+			printOptionDetail(nested, indent+"      ")
+		}
+	}
+	for _, input := range opt.Inputs {
+		line := fmt.Sprintf("%s  - %s (%s)", indent, input.Name, input.Label)
+		if input.PipelineType != "" {
+			line += " 类型: " + input.PipelineType
+		}
+		if input.Default != nil {
+			line += fmt.Sprintf(" 默认: %v", input.Default)
+		}
+		if input.Verify != "" {
+			line += " 校验: " + input.Verify
+		}
+		fmt.Println(line)
+	}
+}
+
+// printJSON 以缩进 JSON 输出到标准输出
+func printJSON(v interface{}) int {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "序列化失败: %v\n", err)
+		return exitFailure
+	}
+	fmt.Println(string(data))
+	return exitOK
+}
+
+// joinOrAll 连接列表，为空时表示不限
+func joinOrAll(list []string) string {
+	if len(list) == 0 {
+		return "全部"
+	}
+	return strings.Join(list, ", ")
+}
+
+// yesNo 布尔值的中文显示
+func yesNo(v bool) string {
+	if v {
+		return "是"
+	}
+	return "否"
+}
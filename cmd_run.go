@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"maaend-client/client"
+	"maaend-client/config"
+	"maaend-client/core"
+	"maaend-client/maa"
+)
+
+// runRunCommand 在本地直接执行任务
+func runRunCommand(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	cfgPath := fs.String("c", "", "配置文件路径")
+	maaEnd := fs.String("maaend", "", "MaaEnd 安装路径")
+	controller := fs.String("controller", "", "控制器名称（为空使用第一个）")
+	resource := fs.String("resource", "", "资源名称（为空使用第一个）")
+	var tasks, options stringList
+	fs.Var(&tasks, "task", "任务名称，可重复指定，按顺序执行")
+	fs.Var(&options, "option", "选项 key=value，可重复指定；value 为合法 JSON 时按 JSON 解析")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if len(tasks) == 0 {
+		fmt.Fprintln(os.Stderr, "至少需要指定一个 -task")
+		fs.Usage()
+		return exitUsage
+	}
+
+	userOptions, err := parseOptionArgs(options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	// 不自动提权：提权后的进程在新窗口中运行，当前进程的退出码无法反映任务结果
+	if err := checkAdmin(); err != nil {
+		fmt.Fprintf(os.Stderr, "需要管理员权限: %v\n", err)
+		return exitFailure
+	}
+
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return exitFailure
+	}
+	if *maaEnd != "" {
+		cfg.MaaEnd.Path = *maaEnd
+	}
+	if cfg.MaaEnd.Path == "" {
+		fmt.Fprintln(os.Stderr, "未找到 MaaEnd 安装目录，请使用 -maaend 参数指定")
+		return exitUsage
+	}
+
+	wrapper := maa.NewWrapper(cfg.MaaEnd.Path)
+	if err := wrapper.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化 MaaFramework 失败: %v\n", err)
+		return exitFailure
+	}
+	defer wrapper.Cleanup()
+
+	job, err := buildRunJob(wrapper.GetProjectInterface(), *controller, *resource, tasks, userOptions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	return executeRunJob(wrapper, job)
+}
+
+// parseOptionArgs 解析 -option key=value 参数
+func parseOptionArgs(args []string) (map[string]interface{}, error) {
+	options := make(map[string]interface{})
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("选项格式应为 key=value: %q", arg)
+		}
+
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(value), &parsed); err == nil {
+			options[key] = parsed
+		} else {
+			options[key] = value
+		}
+	}
+	return options, nil
+}
+
+// buildRunJob 校验参数并构建任务，执行前预先解析所有选项
+func buildRunJob(pi *core.ProjectInterface, controller, resource string, tasks []string, userOptions map[string]interface{}) (*client.Job, error) {
+	if controller == "" && len(pi.Controllers) > 0 {
+		controller = pi.Controllers[0].Name
+	}
+	if pi.GetController(controller) == nil {
+		return nil, fmt.Errorf("控制器不存在: %s (可选: %s)", controller, strings.Join(pi.GetControllerNames(), ", "))
+	}
+	if resource == "" && len(pi.Resources) > 0 {
+		resource = pi.Resources[0].Name
+	}
+	if pi.GetResource(resource) == nil {
+		return nil, fmt.Errorf("资源不存在: %s (可选: %s)", resource, strings.Join(pi.GetResourceNames(), ", "))
+	}
+
+	resolver := core.NewOptionResolver(pi)
+	usedOptions := make(map[string]bool)
+	items := make([]client.RunTaskItem, 0, len(tasks))
+	for _, name := range tasks {
+		task := pi.GetTask(name)
+		if task == nil {
+			return nil, fmt.Errorf("任务不存在: %s", name)
+		}
+		if len(task.Controller) > 0 && !containsString(task.Controller, controller) {
+			return nil, fmt.Errorf("任务 %s 不支持控制器 %s", name, controller)
+		}
+		if len(task.Resource) > 0 && !containsString(task.Resource, resource) {
+			return nil, fmt.Errorf("任务 %s 不支持资源 %s", name, resource)
+		}
+
+		// 每个任务只接收自己声明的选项（含 case 嵌套选项）
+		taskOptions := make(map[string]interface{})
+		for _, opt := range pi.TaskOptionNames(name) {
+			if value, ok := userOptions[opt]; ok {
+				taskOptions[opt] = normalizeCaseValue(pi.GetOption(opt), value)
+				usedOptions[opt] = true
+			}
+		}
+
+		if issues := resolver.CheckTaskOptions(name, taskOptions); len(issues) > 0 {
+			return nil, &core.OptionError{Issues: issues}
+		}
+		if _, err := resolver.ResolveTaskOptions(name, taskOptions); err != nil {
+			return nil, fmt.Errorf("任务 %s: %w", name, err)
+		}
+
+		items = append(items, client.RunTaskItem{Name: name, Options: taskOptions})
+	}
+
+	for key := range userOptions {
+		if pi.GetOption(key) == nil {
+			return nil, fmt.Errorf("选项不存在: %s", key)
+		}
+		if !usedOptions[key] {
+			return nil, fmt.Errorf("选项 %s 不属于所选任务", key)
+		}
+	}
+
+	strict := true
+	return &client.Job{
+		JobID:      fmt.Sprintf("cli-%d", time.Now().Unix()),
+		Controller: controller,
+		Resource:   resource,
+		Tasks:      items,
+		StartTime:  time.Now(),
+		Status:     "running",
+		Origin:     client.JobOriginLocal,
+
+		StrictOptions: &strict, // 选项已在上面校验
+	}, nil
+}
+
+// normalizeCaseValue 将 select/switch 选项的 JSON 标量还原为 case 名称（如 -option Stage=7 解析出的数字）
+func normalizeCaseValue(opt *core.OptionConfig, value interface{}) interface{} {
+	if opt == nil || (opt.Type != "select" && opt.Type != "switch") {
+		return value
+	}
+	switch value.(type) {
+	case float64, bool:
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+	return value
+}
+
+// executeRunJob 执行任务并在终端输出状态和日志，返回退出码
+func executeRunJob(wrapper *maa.Wrapper, job *client.Job) int {
+	fmt.Printf("控制器: %s, 资源: %s, 任务: %d 个\n", job.Controller, job.Resource, len(job.Tasks))
+
+	statusCh := make(chan client.TaskStatusPayload, 100)
+	logCh := make(chan client.TaskLogPayload, 1000)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for status := range statusCh {
+			fmt.Printf("%s [状态] %d/%d %s\n", time.Now().Format("15:04:05"),
+				status.Progress.Completed, status.Progress.Total, status.Message)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for entry := range logCh {
+			fmt.Printf("%s [%s] %s\n", time.Now().Format("15:04:05"), entry.Level, entry.Message)
+		}
+	}()
+
+	// Ctrl+C 时停止任务
+	var interrupted bool
+	var mu sync.Mutex
+	sigCh := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(sigCh)
+		close(done)
+	}()
+	go func() {
+		select {
+		case <-sigCh:
+			mu.Lock()
+			interrupted = true
+			mu.Unlock()
+			fmt.Println("\n收到退出信号，正在停止任务...")
+			wrapper.StopTask()
+		case <-done:
+		}
+	}()
+
+	start := time.Now()
+	err := wrapper.RunTask(job, statusCh, logCh)
+	wrapper.ClearEventChannels()
+	close(statusCh)
+	close(logCh)
+	wg.Wait()
+
+	duration := time.Since(start).Round(time.Millisecond)
+
+	mu.Lock()
+	wasInterrupted := interrupted
+	mu.Unlock()
+
+	switch {
+	case wasInterrupted:
+		fmt.Printf("任务已中断，耗时: %s\n", duration)
+		return exitInterrupted
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "任务执行失败: %v\n", err)
+		fmt.Printf("耗时: %s\n", duration)
+		return exitFailure
+	default:
+		fmt.Printf("任务执行完成，耗时: %s\n", duration)
+		return exitOK
+	}
+}
+
+// containsString 检查列表是否包含指定字符串
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 子命令退出码
+const (
+	exitOK          = 0   // 成功
+	exitFailure     = 1   // 执行失败
+	exitUsage       = 2   // 参数错误
+	exitInterrupted = 130 // 被 Ctrl+C 中断
+)
+
+// command 子命令
+type command struct {
+	summary string
+	run     func(args []string) int
+}
+
+// commands 子命令列表（不带子命令时以客户端模式运行）
+var commands = map[string]command{
+	"audit":    {summary: "查看服务器指令审计日志", run: runAuditCommand},
+	"describe": {summary: "查看任务或选项详情（选项树、输入字段、默认值）", run: runDescribeCommand},
+	"doctor":   {summary: "检查运行环境（MaaEnd 目录、interface.json、窗口与设备、配置、服务器连通性）", run: runDoctorCommand},
+	"explain":  {summary: "显示任务选项的解析过程：选中的 case 及原因、嵌套选项和被覆盖的键", run: runExplainCommand},
+	"lint":     {summary: "校验 interface.json 及其导入文件中的引用和翻译", run: runLintCommand},
+	"list":     {summary: "列出任务、控制器、资源或选项", run: runListCommand},
+	"run":      {summary: "在本地直接执行任务（不连接服务器）", run: runRunCommand},
+}
+
+// runSubcommand 执行子命令，args 不以子命令开头时返回 false
+func runSubcommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return 0, false
+	}
+	return cmd.run(args[1:]), true
+}
+
+// printUsage 输出帮助信息（含子命令列表）
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "用法: %s [参数]\n", os.Args[0])
+	fmt.Fprintf(out, "      %s <子命令> [参数]\n\n参数:\n", os.Args[0])
+	flag.PrintDefaults()
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(out, "\n子命令:")
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
+
+// stringList 可重复指定的字符串参数
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"time"
@@ -14,19 +15,65 @@ import (
 
 // Config 全局配置
 type Config struct {
-	Version string        `mapstructure:"version"` // 客户端版本号
-	Server  ServerConfig  `mapstructure:"server"`
-	MaaEnd  MaaEndConfig  `mapstructure:"maaend"`
-	Device  DeviceConfig  `mapstructure:"device"`
-	Logging LoggingConfig `mapstructure:"logging"`
+	Version  string         `mapstructure:"version"` // 客户端版本号
+	Server   ServerConfig   `mapstructure:"server"`
+	MaaEnd   MaaEndConfig   `mapstructure:"maaend"`
+	Device   DeviceConfig   `mapstructure:"device"`
+	Security SecurityConfig `mapstructure:"security"`
+	LocalAPI LocalAPIConfig `mapstructure:"local_api"`
+	Logging  LoggingConfig  `mapstructure:"logging"`
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
 	WsURL             string        `mapstructure:"ws_url"`
+	WsURLs            []string      `mapstructure:"ws_urls"`      // 多个服务器地址（非空时优先于 ws_url）
+	URLStrategy       string        `mapstructure:"url_strategy"` // 多地址选择策略
+	Transport         string        `mapstructure:"transport"`    // 传输方式: auto, websocket, longpoll, sse
 	ConnectTimeout    time.Duration `mapstructure:"connect_timeout"`
 	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
+	ReconnectMinDelay time.Duration `mapstructure:"reconnect_min_delay"`
 	ReconnectMaxDelay time.Duration `mapstructure:"reconnect_max_delay"`
+	TLS               TLSConfig     `mapstructure:"tls"`
+	Proxy             string        `mapstructure:"proxy"` // 代理地址，为空读取环境变量，direct 表示直连
+
+	// AllowedRedirectHosts going_away 备用地址允许的主机（支持 *.example.com），ws_urls 之外的备用地址需签名且使用 wss/https
+	AllowedRedirectHosts []string `mapstructure:"allowed_redirect_hosts"`
+
+	// URLOverride 命令行 -server 指定的地址，优先于配置文件，不会写回配置
+	URLOverride string `mapstructure:"-"`
+}
+
+// Endpoints 获取服务器地址列表
+// 优先级：命令行覆盖 > ws_urls > ws_url
+func (s *ServerConfig) Endpoints() []string {
+	if s.URLOverride != "" {
+		return []string{s.URLOverride}
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	for _, u := range s.WsURLs {
+		u = strings.TrimSpace(u)
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		urls = append(urls, u)
+	}
+	if len(urls) == 0 && s.WsURL != "" {
+		urls = append(urls, s.WsURL)
+	}
+	return urls
+}
+
+// TLSConfig TLS 配置
+type TLSConfig struct {
+	CAFile     string   `mapstructure:"ca_file"`     // 自定义 CA 证书（PEM）
+	CertFile   string   `mapstructure:"cert_file"`   // 客户端证书（双向 TLS）
+	KeyFile    string   `mapstructure:"key_file"`    // 客户端私钥
+	Pins       []string `mapstructure:"pins"`        // 证书公钥指纹（SPKI SHA-256，base64）
+	MinVersion string   `mapstructure:"min_version"` // 最低 TLS 版本: 1.2, 1.3
 }
 
 // MaaEndConfig MaaEnd 配置
@@ -34,43 +81,100 @@ type MaaEndConfig struct {
 	Path             string `mapstructure:"path"`
 	Win32ClassRegex  string `mapstructure:"win32_class_regex"`
 	Win32WindowRegex string `mapstructure:"win32_window_regex"`
+	StrictOptions    bool   `mapstructure:"strict_options"` // 任务选项有未声明的键、类型错误或 case 不存在时拒绝执行
+
+	Language         string   `mapstructure:"language"`          // 上报能力使用的默认语言
+	LanguageFallback []string `mapstructure:"language_fallback"` // 缺少翻译时依次尝试的语言
+
+	WatchFiles bool `mapstructure:"watch_files"` // 监视 interface.json 等文件和资源目录，变化后在任务间隙自动重新加载
 }
 
 // DeviceConfig 设备配置
 type DeviceConfig struct {
 	Name  string `mapstructure:"name"`
-	Token string `mapstructure:"token"`
+	Token string `mapstructure:"token"` // 已废弃：旧版本保存在配置文件中的令牌，启动时迁移到本地存储，不再写回
+}
+
+// SecurityConfig 安全配置
+type SecurityConfig struct {
+	CommandSigning  string        `mapstructure:"command_signing"`  // 服务器指令签名策略: off, optional, required
+	SignatureWindow time.Duration `mapstructure:"signature_window"` // 签名时间戳允许的最大偏差
+	CredentialKey   string        `mapstructure:"credential_key"`   // 本地凭证加密密钥来源: machine, passphrase
+	PolicyFile      string        `mapstructure:"policy_file"`      // 本地指令权限策略文件（为空不限制）
+}
+
+// CredentialPassphraseEnv 凭证口令环境变量（security.credential_key 为 passphrase 时使用）
+const CredentialPassphraseEnv = "MAAEND_CREDENTIAL_PASSPHRASE"
+
+// LocalAPIConfig 本地控制 API 配置
+type LocalAPIConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Listen  string `mapstructure:"listen"` // 监听地址
+	Token   string `mapstructure:"token"`  // 访问令牌（为空时自动生成）
 }
 
 // LoggingConfig 日志配置
 type LoggingConfig struct {
-	Level string `mapstructure:"level"`
-	File  string `mapstructure:"file"`
+	Level           string `mapstructure:"level"`
+	File            string `mapstructure:"file"`
+	AuditFile       string `mapstructure:"audit_file"`        // 审计日志文件（为空则不记录）
+	AuditMaxSizeMB  int    `mapstructure:"audit_max_size_mb"` // 单个审计日志文件最大大小（MB）
+	AuditMaxBackups int    `mapstructure:"audit_max_backups"` // 保留的轮转文件数量
 }
 
 var globalConfig *Config
 var configFilePath string
 
-// Load 加载配置
-func Load(configPath string) (*Config, error) {
+// newViper 创建带默认值的 viper 实例
+func newViper() *viper.Viper {
 	v := viper.New()
 
 	// 设置默认值
 	v.SetDefault("version", "0.3.0")
 	v.SetDefault("server.ws_url", "wss://end-api.shallow.ink/ws/maaend")
+	v.SetDefault("server.ws_urls", []string{})
+	v.SetDefault("server.allowed_redirect_hosts", []string{})
+	v.SetDefault("server.url_strategy", "failover")
+	v.SetDefault("server.transport", "auto")
 	v.SetDefault("server.connect_timeout", "10s")
 	v.SetDefault("server.heartbeat_interval", "30s")
+	v.SetDefault("server.reconnect_min_delay", "1s")
 	v.SetDefault("server.reconnect_max_delay", "30s")
+	v.SetDefault("server.proxy", "")
+	v.SetDefault("server.tls.ca_file", "")
+	v.SetDefault("server.tls.cert_file", "")
+	v.SetDefault("server.tls.key_file", "")
+	v.SetDefault("server.tls.pins", []string{})
+	v.SetDefault("server.tls.min_version", "1.2")
 	v.SetDefault("maaend.path", "")
 	v.SetDefault("maaend.win32_class_regex", "")
 	v.SetDefault("maaend.win32_window_regex", "")
+	v.SetDefault("maaend.strict_options", false)
+	v.SetDefault("maaend.language", "zh_cn")
+	v.SetDefault("maaend.language_fallback", []string{"zh_cn"})
+	v.SetDefault("maaend.watch_files", true)
 	v.SetDefault("device.name", "")
 	v.SetDefault("device.token", "")
+	v.SetDefault("security.command_signing", "optional")
+	v.SetDefault("security.signature_window", "5m")
+	v.SetDefault("security.credential_key", "machine")
+	v.SetDefault("security.policy_file", "")
+	v.SetDefault("local_api.enabled", false)
+	v.SetDefault("local_api.listen", "127.0.0.1:15619")
+	v.SetDefault("local_api.token", "")
 	v.SetDefault("logging.level", "info")
 	v.SetDefault("logging.file", "")
+	v.SetDefault("logging.audit_file", "audit.log")
+	v.SetDefault("logging.audit_max_size_mb", 10)
+	v.SetDefault("logging.audit_max_backups", 5)
 
-	configFilePath = resolveConfigPath(configPath)
-	v.SetConfigFile(configFilePath)
+	return v
+}
+
+// readConfig 读取并解析配置文件（文件不存在时使用默认值）
+func readConfig(path string) (*Config, error) {
+	v := newViper()
+	v.SetConfigFile(path)
 
 	// 读取配置文件
 	if err := v.ReadInConfig(); err != nil {
@@ -89,6 +193,17 @@ func Load(configPath string) (*Config, error) {
 	if cfg.Version == "" {
 		cfg.Version = "0.3.0"
 	}
+	return &cfg, nil
+}
+
+// Load 加载配置
+func Load(configPath string) (*Config, error) {
+	configFilePath = resolveConfigPath(configPath)
+	loaded, err := readConfig(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	cfg := *loaded
 
 	// 自动检测 MaaEnd 路径
 	if cfg.MaaEnd.Path == "" {
@@ -114,25 +229,29 @@ func Get() *Config {
 	return globalConfig
 }
 
-// SaveToken 保存设备令牌到配置文件
-func SaveToken(token string) error {
+// ClearLegacyToken 从配置文件中移除旧版本保存的设备令牌
+func ClearLegacyToken() error {
 	if globalConfig == nil {
 		return fmt.Errorf("配置未加载")
 	}
 
-	globalConfig.Device.Token = token
+	globalConfig.Device.Token = ""
 
-	// 使用模板保存配置，保持格式整洁
+	// 模板不再包含 token 字段，重新保存即可移除
 	return SaveConfig()
 }
 
 // SaveConfig 保存完整配置到文件
 func SaveConfig() error {
+	return SaveConfigTo(getConfigFilePath())
+}
+
+// SaveConfigTo 保存完整配置到指定文件
+func SaveConfigTo(path string) error {
 	if globalConfig == nil {
 		return fmt.Errorf("配置未加载")
 	}
 
-	path := getConfigFilePath()
 	dir := filepath.Dir(path)
 	if dir != "" && dir != "." {
 		if err := os.MkdirAll(dir, 0755); err != nil {
@@ -148,12 +267,35 @@ version: "%s"
 server:
   # 云端 WebSocket 地址
   ws_url: "%s"
+  # 多个服务器地址（非空时优先于 ws_url，例如主备节点）
+  ws_urls: %s
+  # 服务器 going_away 备用地址允许的主机（如 "*.example.com"）；为空时只接受 ws_urls 中的地址，
+  # 其他主机的备用地址需通过指令签名校验且使用 wss/https
+  allowed_redirect_hosts: %s
+  # 多地址选择策略: failover（按顺序故障转移）, round_robin（轮询）, sticky（优先上次成功的地址）
+  url_strategy: "%s"
+  # 传输方式: auto（ws/wss 使用 WebSocket，http/https 使用长轮询）, websocket, longpoll, sse
+  transport: "%s"
   # 连接超时
   connect_timeout: %s
   # 心跳间隔
   heartbeat_interval: %s
-  # 重连最大延迟
+  # 重连最小延迟
+  reconnect_min_delay: %s
+  # 重连最大延迟（实际延迟在最小值与退避上限之间随机）
   reconnect_max_delay: %s
+  # 代理地址（http://、https://、socks5://，可带 user:pass@），为空读取 HTTPS_PROXY/NO_PROXY 环境变量，direct 表示不使用代理
+  proxy: "%s"
+  tls:
+    # 自定义 CA 证书文件（PEM，为空则使用系统证书）
+    ca_file: "%s"
+    # 客户端证书和私钥（PEM，用于双向 TLS，为空则不发送）
+    cert_file: "%s"
+    key_file: "%s"
+    # 证书公钥指纹（SPKI SHA-256，base64），非空时服务器证书链必须匹配其中之一
+    pins: %s
+    # 最低 TLS 版本: 1.2, 1.3
+    min_version: "%s"
 
 maaend:
   # MaaEnd 安装目录（为空则自动检测）
@@ -162,11 +304,35 @@ maaend:
   win32_class_regex: "%s"
   # 覆盖 Win32 窗口标题（正则，留空不覆盖）
   win32_window_regex: "%s"
+  # 严格校验任务选项（未声明的选项、类型错误、case 不存在时任务失败；关闭时仅上报警告日志）
+  strict_options: %t
+  # 上报给服务器的任务、选项名称使用的语言（服务器可通过 request_capabilities 指定其他语言）
+  language: "%s"
+  # 缺少翻译时依次尝试的语言，仍找不到时按语言代码顺序使用任一翻译
+  language_fallback: %s
+  # 监视 interface.json、import 文件、翻译文件和当前资源目录，变化后在任务间隙自动重新加载
+  watch_files: %t
 
 device:
   # 设备名称（为空则使用主机名）
   name: "%s"
-  # 设备令牌（首次绑定后自动保存）
+
+security:
+  # 服务器指令签名策略: off（不校验）, optional（有签名时校验）, required（拒绝未签名指令）
+  command_signing: "%s"
+  # 签名时间戳允许的最大偏差（同时也是防重放的 nonce 记录时长）
+  signature_window: %s
+  # 本地凭证加密密钥来源: machine（由本机机器标识派生）, passphrase（由环境变量 MAAEND_CREDENTIAL_PASSPHRASE 中的口令派生）
+  credential_key: "%s"
+  # 本地指令权限策略文件（YAML，相对路径基于配置文件目录，为空则不限制服务器可执行的指令和任务）
+  policy_file: "%s"
+
+local_api:
+  # 是否启用本地控制 API（供局域网内的自动化工具直接控制，无需云端服务器）
+  enabled: %t
+  # 监听地址（127.0.0.1 仅本机访问，0.0.0.0 允许局域网访问）
+  listen: "%s"
+  # 访问令牌（请求头 Authorization: Bearer <token>），留空时首次启动自动生成并保存到 local_api.token
   token: "%s"
 
 logging:
@@ -174,24 +340,125 @@ logging:
   level: "%s"
   # 日志文件（为空则输出到控制台）
   file: "%s"
+  # 审计日志文件（记录所有服务器指令及其结果，相对路径基于配置文件目录，为空则不记录）
+  audit_file: "%s"
+  # 单个审计日志文件最大大小（MB），超出后轮转
+  audit_max_size_mb: %d
+  # 保留的审计日志轮转文件数量
+  audit_max_backups: %d
 `,
 		globalConfig.Version,
 		globalConfig.Server.WsURL,
+		formatStringList(globalConfig.Server.WsURLs, "    "),
+		formatStringList(globalConfig.Server.AllowedRedirectHosts, "    "),
+		globalConfig.Server.URLStrategy,
+		globalConfig.Server.Transport,
 		globalConfig.Server.ConnectTimeout,
 		globalConfig.Server.HeartbeatInterval,
+		globalConfig.Server.ReconnectMinDelay,
 		globalConfig.Server.ReconnectMaxDelay,
+		globalConfig.Server.Proxy,
+		globalConfig.Server.TLS.CAFile,
+		globalConfig.Server.TLS.CertFile,
+		globalConfig.Server.TLS.KeyFile,
+		formatStringList(globalConfig.Server.TLS.Pins, "      "),
+		globalConfig.Server.TLS.MinVersion,
 		globalConfig.MaaEnd.Path,
 		globalConfig.MaaEnd.Win32ClassRegex,
 		globalConfig.MaaEnd.Win32WindowRegex,
+		globalConfig.MaaEnd.StrictOptions,
+		globalConfig.MaaEnd.Language,
+		formatStringList(globalConfig.MaaEnd.LanguageFallback, "    "),
+		globalConfig.MaaEnd.WatchFiles,
 		globalConfig.Device.Name,
-		globalConfig.Device.Token,
+		globalConfig.Security.CommandSigning,
+		globalConfig.Security.SignatureWindow,
+		globalConfig.Security.CredentialKey,
+		globalConfig.Security.PolicyFile,
+		globalConfig.LocalAPI.Enabled,
+		globalConfig.LocalAPI.Listen,
+		globalConfig.LocalAPI.Token,
 		globalConfig.Logging.Level,
 		globalConfig.Logging.File,
+		globalConfig.Logging.AuditFile,
+		globalConfig.Logging.AuditMaxSizeMB,
+		globalConfig.Logging.AuditMaxBackups,
 	)
 
 	return os.WriteFile(path, []byte(configContent), 0644)
 }
 
+// VerifyRoundTrip 将当前配置写入临时文件并重新读取，检查保存后内容是否保持一致
+func VerifyRoundTrip() error {
+	if globalConfig == nil {
+		return fmt.Errorf("配置未加载")
+	}
+
+	tmp, err := os.CreateTemp("", "maaend-config-*.yaml")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	if err := SaveConfigTo(tmpPath); err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+	reloaded, err := readConfig(tmpPath)
+	if err != nil {
+		return fmt.Errorf("重新读取配置失败: %w", err)
+	}
+
+	// 已废弃的令牌字段和命令行覆盖不会写入配置文件
+	want := *globalConfig
+	want.Device.Token = ""
+	want.Server.URLOverride = ""
+	if diff := diffFields(reflect.ValueOf(want), reflect.ValueOf(*reloaded), ""); len(diff) > 0 {
+		return fmt.Errorf("保存后重新读取的配置与当前配置不一致: %s", strings.Join(diff, ", "))
+	}
+	return nil
+}
+
+// diffFields 递归比较两个配置结构体，返回不一致的字段（按 mapstructure 名称）
+func diffFields(a, b reflect.Value, prefix string) []string {
+	var diff []string
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if field.Type.Kind() == reflect.Struct {
+			diff = append(diff, diffFields(a.Field(i), b.Field(i), name)...)
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			diff = append(diff, name)
+		}
+	}
+	return diff
+}
+
+// formatStringList 将字符串列表格式化为 YAML（空列表输出 []）
+func formatStringList(values []string, indent string) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	var b strings.Builder
+	for _, v := range values {
+		b.WriteString("\n")
+		b.WriteString(indent)
+		b.WriteString(`- "`)
+		b.WriteString(v)
+		b.WriteString(`"`)
+	}
+	return b.String()
+}
+
 // EnsureConfigFormat 检查并修复配置文件格式
 func EnsureConfigFormat() error {
 	// 检查配置文件是否存在
@@ -211,6 +478,19 @@ func EnsureConfigFormat() error {
 	return nil
 }
 
+// FilePath 获取当前使用的配置文件路径
+func FilePath() string {
+	return getConfigFilePath()
+}
+
+// ResolvePath 将相对路径解析为相对于配置文件所在目录的路径
+func ResolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(getConfigFilePath()), path)
+}
+
 func resolveConfigPath(configPath string) string {
 	if configPath != "" {
 		if abs, err := filepath.Abs(configPath); err == nil {
@@ -244,13 +524,13 @@ func getConfigFilePath() string {
 // detectMaaEndPath 自动检测 MaaEnd 安装路径
 func detectMaaEndPath() string {
 	// 1. 检查当前目录
-	if isMaaEndDir(".") {
+	if IsMaaEndDir(".") {
 		absPath, _ := filepath.Abs(".")
 		return absPath
 	}
 
 	// 2. 检查当前目录的父目录（如果 client 在 MaaEnd 目录内）
-	if isMaaEndDir("..") {
+	if IsMaaEndDir("..") {
 		absPath, _ := filepath.Abs("..")
 		return absPath
 	}
@@ -260,7 +540,7 @@ func detectMaaEndPath() string {
 		appData := os.Getenv("APPDATA")
 		if appData != "" {
 			maaEndPath := filepath.Join(appData, "MaaEnd")
-			if isMaaEndDir(maaEndPath) {
+			if IsMaaEndDir(maaEndPath) {
 				return maaEndPath
 			}
 		}
@@ -275,7 +555,7 @@ func detectMaaEndPath() string {
 	}
 
 	for _, p := range commonPaths {
-		if isMaaEndDir(p) {
+		if IsMaaEndDir(p) {
 			return p
 		}
 	}
@@ -283,8 +563,8 @@ func detectMaaEndPath() string {
 	return ""
 }
 
-// isMaaEndDir 检查目录是否是 MaaEnd 安装目录
-func isMaaEndDir(path string) bool {
+// IsMaaEndDir 检查目录是否是 MaaEnd 安装目录
+func IsMaaEndDir(path string) bool {
 	// 检查 interface.json 是否存在
 	interfacePath := filepath.Join(path, "interface.json")
 	if _, err := os.Stat(interfacePath); err != nil {
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"sort"
+
 	"maaend-client/client"
 )
 
@@ -27,15 +29,23 @@ func (b *CapabilitiesBuilder) Build() *client.CapabilitiesPayload {
 		Controllers: b.pi.GetControllerNames(),
 		Resources:   b.pi.GetResourceNames(),
 		Tasks:       make([]client.TaskInfo, 0, len(b.pi.Tasks)),
+
+		OptionDependencies: b.pi.OptionGraph().Edges(),
+
+		Language:  b.lang,
+		Languages: b.declaredLanguages(),
 	}
 
 	// 构建任务信息
 	for _, task := range b.pi.Tasks {
 		taskInfo := client.TaskInfo{
-			Name:        task.Name,
-			Label:       b.pi.GetI18nString(task.Label, b.lang),
-			Description: b.pi.GetI18nString(task.Description, b.lang),
-			Options:     b.buildTaskOptions(task.Option),
+			Name:         task.Name,
+			Label:        b.pi.GetI18nString(task.Label, b.lang),
+			Description:  b.pi.GetI18nString(task.Description, b.lang),
+			DefaultCheck: task.DefaultCheck,
+			Controllers:  task.Controller,
+			Resources:    task.Resource,
+			Options:      b.buildTaskOptions(task.Option),
 		}
 		capabilities.Tasks = append(capabilities.Tasks, taskInfo)
 	}
@@ -43,37 +53,73 @@ func (b *CapabilitiesBuilder) Build() *client.CapabilitiesPayload {
 	return capabilities
 }
 
-// buildTaskOptions 构建任务选项
+// BuildAll 构建设备能力，并按 interface.json 声明的每种语言附带任务信息
+func (b *CapabilitiesBuilder) BuildAll() *client.CapabilitiesPayload {
+	capabilities := b.Build()
+	capabilities.Localized = make(map[string][]client.TaskInfo, len(capabilities.Languages))
+	for _, lang := range capabilities.Languages {
+		capabilities.Localized[lang] = NewCapabilitiesBuilder(b.pi, lang).Build().Tasks
+	}
+	return capabilities
+}
+
+// declaredLanguages 获取 interface.json 声明的语言代码（按代码排序）
+func (b *CapabilitiesBuilder) declaredLanguages() []string {
+	langs := make([]string, 0, len(b.pi.Languages))
+	for lang := range b.pi.Languages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// buildTaskOptions 构建任务选项（含 case 嵌套选项和输入字段）
 func (b *CapabilitiesBuilder) buildTaskOptions(optionNames []string) []client.OptionInfo {
 	var options []client.OptionInfo
 
 	for _, optName := range optionNames {
-		opt := b.pi.GetOption(optName)
-		if opt == nil {
+		detail, ok := b.describeOption(optName, nil)
+		if !ok {
 			continue
 		}
+		options = append(options, optionInfo(detail))
+	}
 
-		optInfo := client.OptionInfo{
-			Name:        optName,
-			Type:        opt.Type,
-			Label:       b.pi.GetI18nString(opt.Label, b.lang),
-			DefaultCase: opt.DefaultCase,
-		}
+	return options
+}
 
-		// 构建 cases
-		if len(opt.Cases) > 0 {
-			optInfo.Cases = make([]client.CaseInfo, 0, len(opt.Cases))
-			for _, c := range opt.Cases {
-				caseInfo := client.CaseInfo{
-					Name:  c.Name,
-					Label: b.pi.GetI18nString(c.Label, b.lang),
-				}
-				optInfo.Cases = append(optInfo.Cases, caseInfo)
-			}
+// optionInfo 将选项详情转换为上报服务器的选项信息
+func optionInfo(detail OptionDetail) client.OptionInfo {
+	info := client.OptionInfo{
+		Name:        detail.Name,
+		Type:        detail.Type,
+		Label:       detail.Label,
+		Description: detail.Description,
+		DefaultCase: detail.DefaultCase,
+		Recursive:   detail.Recursive,
+	}
+
+	for _, c := range detail.Cases {
+		caseInfo := client.CaseInfo{
+			Name:  c.Name,
+			Label: c.Label,
+		}
+		for _, nested := range c.Options {
+			caseInfo.Options = append(caseInfo.Options, optionInfo(nested))
 		}
+		info.Cases = append(info.Cases, caseInfo)
+	}
 
-		options = append(options, optInfo)
+	for _, input := range detail.Inputs {
+		info.Inputs = append(info.Inputs, client.InputInfo{
+			Name:         input.Name,
+			Label:        input.Label,
+			Description:  input.Description,
+			PipelineType: input.PipelineType,
+			Verify:       input.Verify,
+			Default:      input.Default,
+		})
 	}
 
-	return options
+	return info
 }
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"maaend-client/client"
+)
+
+func TestBuildCapabilities(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "interface.json", `{
+		"controller": [{"name": "Win32", "type": "Win32"}, {"name": "ADB", "type": "Adb"}],
+		"resource": [{"name": "Official", "path": ["./resource"]}],
+		"languages": {"zh_cn": "zh_cn.json"},
+		"task": [{"name": "Daily", "label": "$task", "entry": "Daily", "default_check": true,
+			"controller": ["Win32"], "option": ["Mode"]}],
+		"option": {
+			"Mode": {"type": "select", "label": "模式", "description": "$mode.desc", "default": "A", "cases": [
+				{"name": "A", "label": "A", "option": ["Count", "Mode"]},
+				{"name": "B", "label": "B"}
+			]},
+			"Count": {"type": "input", "label": "次数", "inputs": [
+				{"name": "count", "label": "次数", "pipeline_type": "int", "verify": "^\\d+$", "default": 3}
+			]}
+		}
+	}`)
+	writeFile(t, dir, "zh_cn.json", `{"task": "日常", "mode.desc": "选择模式"}`)
+
+	pi, err := LoadInterface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caps := NewCapabilitiesBuilder(pi, "zh_cn").Build()
+
+	want := client.TaskInfo{
+		Name:         "Daily",
+		Label:        "日常",
+		DefaultCheck: true,
+		Controllers:  []string{"Win32"},
+		Options: []client.OptionInfo{{
+			Name:        "Mode",
+			Type:        "select",
+			Label:       "模式",
+			Description: "选择模式",
+			DefaultCase: "A",
+			Cases: []client.CaseInfo{
+				{Name: "A", Label: "A", Options: []client.OptionInfo{
+					{Name: "Count", Type: "input", Label: "次数", Inputs: []client.InputInfo{
+						{Name: "count", Label: "次数", PipelineType: "int", Verify: `^\d+$`, Default: float64(3)},
+					}},
+					{Name: "Mode", Type: "select", Label: "模式", Description: "选择模式", DefaultCase: "A", Recursive: true},
+				}},
+				{Name: "B", Label: "B"},
+			},
+		}},
+	}
+	if len(caps.Tasks) != 1 || !reflect.DeepEqual(caps.Tasks[0], want) {
+		t.Fatalf("Tasks = %+v\nwant  %+v", caps.Tasks, want)
+	}
+	if deps := caps.OptionDependencies; !reflect.DeepEqual(deps, map[string][]string{"Mode": {"Count", "Mode"}}) {
+		t.Fatalf("OptionDependencies = %v", deps)
+	}
+}
+
+func TestCapabilitiesLanguages(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "interface.json", `{
+		"languages": {"zh_cn": "zh_cn.json", "en_us": "en_us.json", "ja_jp": "ja_jp.json"},
+		"task": [{"name": "Daily", "label": "$daily", "description": "$daily.desc", "entry": "Daily"}]
+	}`)
+	writeFile(t, dir, "zh_cn.json", `{"daily": "日常", "daily.desc": "领取奖励"}`)
+	writeFile(t, dir, "en_us.json", `{"daily": "Daily", "daily.desc": "Claim rewards"}`)
+	writeFile(t, dir, "ja_jp.json", `{"daily": "デイリー"}`)
+
+	pi, err := LoadInterface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 默认回退到 zh_cn
+	if got := pi.GetI18nString("$daily.desc", "ja_jp"); got != "领取奖励" {
+		t.Errorf("默认回退 = %q", got)
+	}
+	pi.SetLanguageFallback([]string{"en_us", "zh_cn"})
+	if got := pi.GetI18nString("$daily.desc", "ja_jp"); got != "Claim rewards" {
+		t.Errorf("en_us 回退 = %q", got)
+	}
+	// 回退列表中都没有时按语言代码顺序（en_us < ja_jp < zh_cn）
+	pi.SetLanguageFallback(nil)
+	if got := pi.GetI18nString("$daily.desc", "fr_fr"); got != "Claim rewards" {
+		t.Errorf("无回退语言 = %q", got)
+	}
+	if got := pi.GetI18nString("$missing", "en_us"); got != "$missing" {
+		t.Errorf("缺失的 key = %q", got)
+	}
+
+	pi.SetLanguageFallback([]string{"zh_cn"})
+	caps := NewCapabilitiesBuilder(pi, "ja_jp").BuildAll()
+	if caps.Language != "ja_jp" || caps.Tasks[0].Label != "デイリー" || caps.Tasks[0].Description != "领取奖励" {
+		t.Errorf("ja_jp = %s %+v", caps.Language, caps.Tasks[0])
+	}
+	if !reflect.DeepEqual(caps.Languages, []string{"en_us", "ja_jp", "zh_cn"}) {
+		t.Errorf("Languages = %v", caps.Languages)
+	}
+	for lang, want := range map[string]string{"en_us": "Daily", "ja_jp": "デイリー", "zh_cn": "日常"} {
+		if tasks := caps.Localized[lang]; len(tasks) != 1 || tasks[0].Label != want {
+			t.Errorf("Localized[%s] = %+v", lang, tasks)
+		}
+	}
+	if caps := NewCapabilitiesBuilder(pi, "en_us").Build(); caps.Localized != nil {
+		t.Errorf("Build() 不应包含 Localized")
+	}
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TaskDetail 任务详情（用于命令行查看）
+type TaskDetail struct {
+	Name         string         `json:"name"`
+	Label        string         `json:"label"`
+	Description  string         `json:"description,omitempty"`
+	Entry        string         `json:"entry"`
+	DefaultCheck bool           `json:"default_check"`
+	Controllers  []string       `json:"controllers,omitempty"` // 为空表示支持所有控制器
+	Resources    []string       `json:"resources,omitempty"`   // 为空表示支持所有资源
+	Options      []OptionDetail `json:"options,omitempty"`
+}
+
+// OptionDetail 选项详情，包含嵌套在 case 中的子选项
+type OptionDetail struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`
+	Label       string        `json:"label"`
+	Description string        `json:"description,omitempty"`
+	DefaultCase string        `json:"default_case,omitempty"`
+	Cases       []CaseDetail  `json:"cases,omitempty"`
+	Inputs      []InputDetail `json:"inputs,omitempty"`
+	Recursive   bool          `json:"recursive,omitempty"` // 选项引用了自身的祖先，不再展开
+}
+
+// CaseDetail 选项分支详情
+type CaseDetail struct {
+	Name    string         `json:"name"`
+	Label   string         `json:"label"`
+	Options []OptionDetail `json:"options,omitempty"`
+}
+
+// InputDetail 输入字段详情
+type InputDetail struct {
+	Name         string      `json:"name"`
+	Label        string      `json:"label"`
+	Description  string      `json:"description,omitempty"`
+	PipelineType string      `json:"pipeline_type,omitempty"`
+	Verify       string      `json:"verify,omitempty"`
+	Default      interface{} `json:"default,omitempty"`
+}
+
+// OptionSummary 选项概要（用于列表）
+type OptionSummary struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Label  string   `json:"label"`
+	UsedBy []string `json:"used_by,omitempty"` // 直接引用该选项的任务
+}
+
+// DescribeTask 构建任务详情
+func (b *CapabilitiesBuilder) DescribeTask(name string) (*TaskDetail, error) {
+	task := b.pi.GetTask(name)
+	if task == nil {
+		return nil, fmt.Errorf("任务不存在: %s", name)
+	}
+
+	detail := &TaskDetail{
+		Name:         task.Name,
+		Label:        b.pi.GetI18nString(task.Label, b.lang),
+		Description:  b.pi.GetI18nString(task.Description, b.lang),
+		Entry:        task.Entry,
+		DefaultCheck: task.DefaultCheck,
+		Controllers:  task.Controller,
+		Resources:    task.Resource,
+	}
+	for _, optName := range task.Option {
+		if opt, ok := b.describeOption(optName, nil); ok {
+			detail.Options = append(detail.Options, opt)
+		}
+	}
+	return detail, nil
+}
+
+// DescribeOption 构建选项详情
+func (b *CapabilitiesBuilder) DescribeOption(name string) (*OptionDetail, error) {
+	opt, ok := b.describeOption(name, nil)
+	if !ok {
+		return nil, fmt.Errorf("选项不存在: %s", name)
+	}
+	return &opt, nil
+}
+
+// ListOptions 列出所有选项（按名称排序）
+func (b *CapabilitiesBuilder) ListOptions() []OptionSummary {
+	usedBy := make(map[string][]string)
+	for _, task := range b.pi.Tasks {
+		for _, optName := range task.Option {
+			usedBy[optName] = append(usedBy[optName], task.Name)
+		}
+	}
+
+	names := make([]string, 0, len(b.pi.Options))
+	for name := range b.pi.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	summaries := make([]OptionSummary, 0, len(names))
+	for _, name := range names {
+		opt := b.pi.Options[name]
+		if opt == nil {
+			continue
+		}
+		summaries = append(summaries, OptionSummary{
+			Name:   name,
+			Type:   opt.Type,
+			Label:  b.pi.GetI18nString(opt.Label, b.lang),
+			UsedBy: usedBy[name],
+		})
+	}
+	return summaries
+}
+
+// describeOption 递归构建选项详情，ancestors 为当前展开路径，用于避免循环引用
+func (b *CapabilitiesBuilder) describeOption(name string, ancestors []string) (OptionDetail, bool) {
+	opt := b.pi.GetOption(name)
+	if opt == nil {
+		return OptionDetail{}, false
+	}
+
+	detail := OptionDetail{
+		Name:        name,
+		Type:        opt.Type,
+		Label:       b.pi.GetI18nString(opt.Label, b.lang),
+		Description: b.pi.GetI18nString(opt.Description, b.lang),
+		DefaultCase: opt.DefaultCase,
+	}
+	if detail.DefaultCase == "" {
+		detail.DefaultCase = opt.Default
+	}
+
+	for _, ancestor := range ancestors {
+		if ancestor == name {
+			detail.Recursive = true
+			return detail, true
+		}
+	}
+	path := append(append([]string(nil), ancestors...), name)
+
+	for _, c := range opt.Cases {
+		caseDetail := CaseDetail{
+			Name:  c.Name,
+			Label: b.pi.GetI18nString(c.Label, b.lang),
+		}
+		for _, nested := range c.Option {
+			if nestedDetail, ok := b.describeOption(nested, path); ok {
+				caseDetail.Options = append(caseDetail.Options, nestedDetail)
+			}
+		}
+		detail.Cases = append(detail.Cases, caseDetail)
+	}
+
+	for _, input := range opt.Inputs {
+		detail.Inputs = append(detail.Inputs, InputDetail{
+			Name:         input.Name,
+			Label:        b.pi.GetI18nString(input.Label, b.lang),
+			Description:  b.pi.GetI18nString(input.Description, b.lang),
+			PipelineType: input.PipelineType,
+			Verify:       input.Verify,
+			Default:      input.Default,
+		})
+	}
+
+	return detail, true
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -28,8 +29,22 @@ type ProjectInterface struct {
 	Import           []string                 `json:"import"` // 外部任务文件路径列表
 
 	// 解析后的国际化文本
-	i18nTexts map[string]map[string]string // lang -> key -> value
-	basePath  string                       // interface.json 所在目录
+	i18nTexts    map[string]map[string]string // lang -> key -> value
+	langFallback []string                     // 缺少翻译时依次尝试的语言
+	basePath     string                       // interface.json 所在目录
+
+	// 定义来源（用于诊断定位）
+	taskSources   []sourceRef          // 与 Tasks 一一对应
+	optionSources map[string]sourceRef // 选项名 -> 定义所在文件
+	loadDiags     []Diagnostic         // 加载过程中的非致命错误
+
+	optionGraph *OptionGraph // 选项依赖图
+}
+
+// sourceRef 定义所在的文件及其在文件中 task 数组的下标
+type sourceRef struct {
+	file  string
+	index int
 }
 
 // ControllerConfig 控制器配置
@@ -142,17 +157,33 @@ func LoadInterface(maaEndPath string) (*ProjectInterface, error) {
 
 	pi.basePath = maaEndPath
 	pi.i18nTexts = make(map[string]map[string]string)
+	pi.langFallback = []string{"zh_cn"}
 
 	// 初始化 Options map（如果为空）
 	if pi.Options == nil {
 		pi.Options = make(map[string]*OptionConfig)
 	}
 
+	// 记录 interface.json 自身定义的任务和选项
+	pi.optionSources = make(map[string]sourceRef)
+	for i := range pi.Tasks {
+		pi.taskSources = append(pi.taskSources, sourceRef{file: interfaceFile, index: i})
+	}
+	for name := range pi.Options {
+		pi.optionSources[name] = sourceRef{file: interfaceFile}
+	}
+
 	// 加载 import 引用的外部任务文件
-	for _, importPath := range pi.Import {
+	for i, importPath := range pi.Import {
 		if err := pi.loadImportedFile(importPath); err != nil {
-			// import 加载失败记录警告但不中断
-			fmt.Printf("警告: 加载导入文件 %s 失败: %v\n", importPath, err)
+			// import 加载失败记录诊断但不中断
+			pi.loadDiags = append(pi.loadDiags, Diagnostic{
+				Severity: SeverityError,
+				Code:     DiagImportFailed,
+				File:     interfaceFile,
+				Path:     fmt.Sprintf("$.import[%d]", i),
+				Message:  fmt.Sprintf("加载导入文件 %s 失败: %v", importPath, err),
+			})
 		}
 	}
 
@@ -160,10 +191,18 @@ func LoadInterface(maaEndPath string) (*ProjectInterface, error) {
 	for lang, path := range pi.Languages {
 		if err := pi.loadI18n(lang, path); err != nil {
 			// 国际化加载失败不是致命错误
-			fmt.Printf("警告: 加载国际化文件 %s 失败: %v\n", path, err)
+			pi.loadDiags = append(pi.loadDiags, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     DiagI18nLoadFailed,
+				File:     interfaceFile,
+				Path:     "$.languages." + lang,
+				Message:  fmt.Sprintf("加载国际化文件 %s 失败: %v", path, err),
+			})
 		}
 	}
 
+	pi.optionGraph = buildOptionGraph(pi.Options)
+
 	return &pi, nil
 }
 
@@ -188,13 +227,27 @@ func (pi *ProjectInterface) loadImportedFile(relativePath string) error {
 	}
 
 	// 合并任务
+	for i := range imported.Tasks {
+		pi.taskSources = append(pi.taskSources, sourceRef{file: relativePath, index: i})
+	}
 	pi.Tasks = append(pi.Tasks, imported.Tasks...)
 
-	// 合并选项
+	// 合并选项（同名选项以后加载的为准）
 	for name, opt := range imported.Options {
-		if opt != nil {
-			pi.Options[name] = opt
+		if opt == nil {
+			continue
+		}
+		if prev, ok := pi.optionSources[name]; ok {
+			pi.loadDiags = append(pi.loadDiags, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     DiagDuplicateOption,
+				File:     relativePath,
+				Path:     "$.option." + name,
+				Message:  fmt.Sprintf("选项 %s 覆盖了 %s 中的同名定义", name, prev.file),
+			})
 		}
+		pi.Options[name] = opt
+		pi.optionSources[name] = sourceRef{file: relativePath}
 	}
 
 	return nil
@@ -217,7 +270,7 @@ func (pi *ProjectInterface) loadI18n(lang, path string) error {
 	return nil
 }
 
-// GetI18nString 获取国际化字符串
+// GetI18nString 获取国际化字符串，依次尝试指定语言、回退语言和其他已加载的语言
 func (pi *ProjectInterface) GetI18nString(key, lang string) string {
 	// 如果不是国际化 key（不以 $ 开头），直接返回
 	if !strings.HasPrefix(key, "$") {
@@ -234,16 +287,18 @@ func (pi *ProjectInterface) GetI18nString(key, lang string) string {
 		}
 	}
 
-	// 回退到中文
-	if texts, ok := pi.i18nTexts["zh_cn"]; ok {
-		if text, ok := texts[realKey]; ok {
-			return text
+	// 按回退顺序尝试
+	for _, fallback := range pi.langFallback {
+		if texts, ok := pi.i18nTexts[fallback]; ok {
+			if text, ok := texts[realKey]; ok {
+				return text
+			}
 		}
 	}
 
-	// 回退到第一个可用语言
-	for _, texts := range pi.i18nTexts {
-		if text, ok := texts[realKey]; ok {
+	// 按语言代码顺序使用任一翻译，保证结果稳定
+	for _, l := range pi.GetLanguages() {
+		if text, ok := pi.i18nTexts[l][realKey]; ok {
 			return text
 		}
 	}
@@ -252,6 +307,21 @@ func (pi *ProjectInterface) GetI18nString(key, lang string) string {
 	return key
 }
 
+// SetLanguageFallback 设置缺少翻译时依次尝试的语言（默认仅 zh_cn）
+func (pi *ProjectInterface) SetLanguageFallback(langs []string) {
+	pi.langFallback = append([]string(nil), langs...)
+}
+
+// GetLanguages 获取已加载翻译的语言代码（按代码排序）
+func (pi *ProjectInterface) GetLanguages() []string {
+	langs := make([]string, 0, len(pi.i18nTexts))
+	for l := range pi.i18nTexts {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
 // GetControllerNames 获取所有控制器名称
 func (pi *ProjectInterface) GetControllerNames() []string {
 	names := make([]string, len(pi.Controllers))
@@ -310,6 +380,23 @@ func (pi *ProjectInterface) GetBasePath() string {
 	return pi.basePath
 }
 
+// InterfaceFiles 获取 interface.json 及其引用的 import 文件、翻译文件的完整路径（用于监视文件变化）
+func (pi *ProjectInterface) InterfaceFiles() []string {
+	files := []string{filepath.Join(pi.basePath, "interface.json")}
+	for _, path := range pi.Import {
+		files = append(files, filepath.Join(pi.basePath, path))
+	}
+	langs := make([]string, 0, len(pi.Languages))
+	for lang := range pi.Languages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		files = append(files, filepath.Join(pi.basePath, pi.Languages[lang]))
+	}
+	return files
+}
+
 // GetAgentExec 获取 Agent 可执行文件完整路径
 func (pi *ProjectInterface) GetAgentExec() string {
 	if pi.Agent.ChildExec == "" {
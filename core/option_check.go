@@ -0,0 +1,203 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"maaend-client/client"
+)
+
+// OptionError 任务选项未通过严格校验
+type OptionError struct {
+	Issues []client.OptionIssue
+}
+
+func (e *OptionError) Error() string {
+	msgs := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		msgs = append(msgs, issue.Message)
+	}
+	return fmt.Sprintf("选项校验失败: %s", strings.Join(msgs, "; "))
+}
+
+// OptionIssues 转换为上报服务器的结构化信息
+func (e *OptionError) OptionIssues() []client.OptionIssue {
+	return e.Issues
+}
+
+// TaskOptionNames 获取任务声明的所有选项（含各 case 的嵌套选项），按首次出现的顺序
+// 未定义的选项不包含在内
+func (pi *ProjectInterface) TaskOptionNames(taskName string) []string {
+	task := pi.GetTask(taskName)
+	if task == nil {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		opt := pi.GetOption(name)
+		if opt == nil || seen[name] {
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+		for _, c := range opt.Cases {
+			for _, nested := range c.Option {
+				visit(nested)
+			}
+		}
+	}
+	for _, name := range task.Option {
+		visit(name)
+	}
+	return names
+}
+
+// CheckTaskOptions 检查用户选项：未在任务中声明的选项、与选项类型不符的值、
+// 不存在的 case 以及未声明的输入。返回按选项名排序的问题，任务不存在时返回 nil
+//
+// 非严格模式下 ResolveTaskOptions 会忽略这些问题（未声明的选项和 case 被跳过，
+// select 的非字符串值回退到默认 case）
+func (r *OptionResolver) CheckTaskOptions(taskName string, userOptions map[string]interface{}) []client.OptionIssue {
+	if r.pi.GetTask(taskName) == nil {
+		return nil
+	}
+	declared := make(map[string]bool)
+	for _, name := range r.pi.TaskOptionNames(taskName) {
+		declared[name] = true
+	}
+
+	keys := make([]string, 0, len(userOptions))
+	for key := range userOptions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var issues []client.OptionIssue
+	add := func(option, code string, value interface{}, format string, args ...interface{}) {
+		issues = append(issues, client.OptionIssue{
+			Task:    taskName,
+			Option:  option,
+			Code:    code,
+			Value:   value,
+			Message: fmt.Sprintf("任务 %s: ", taskName) + fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, key := range keys {
+		value := userOptions[key]
+		if !declared[key] {
+			add(key, client.OptionIssueUnknownOption, nil, "选项 %s 未在任务中声明", key)
+			continue
+		}
+		if value == nil {
+			continue
+		}
+
+		opt := r.pi.GetOption(key)
+		switch opt.Type {
+		case "select", "switch":
+			caseName, ok := value.(string)
+			if !ok {
+				add(key, client.OptionIssueInvalidType, value, "选项 %s 的值应为 case 名称（字符串），实际为%s", key, valueTypeName(value))
+				continue
+			}
+			if !hasCase(opt, caseName) {
+				add(key, client.OptionIssueUnknownCase, caseName, "选项 %s 的 case %s 不存在", key, caseName)
+			}
+		case "checkbox":
+			var items []interface{}
+			switch v := value.(type) {
+			case []interface{}:
+				items = v
+			case []string:
+				for _, s := range v {
+					items = append(items, s)
+				}
+			default:
+				add(key, client.OptionIssueInvalidType, value, "选项 %s 的值应为 case 名称数组，实际为%s", key, valueTypeName(value))
+				continue
+			}
+			for _, item := range items {
+				caseName, ok := item.(string)
+				if !ok {
+					add(key, client.OptionIssueInvalidType, item, "选项 %s 的数组元素应为 case 名称（字符串），实际为%s", key, valueTypeName(item))
+					continue
+				}
+				if !hasCase(opt, strings.TrimSpace(caseName)) {
+					add(key, client.OptionIssueUnknownCase, caseName, "选项 %s 的 case %s 不存在", key, caseName)
+				}
+			}
+		case "input":
+			inputs := make(map[string]interface{})
+			switch v := value.(type) {
+			case map[string]interface{}:
+				inputs = v
+			case map[string]string:
+				for k, s := range v {
+					inputs[k] = s
+				}
+			default:
+				add(key, client.OptionIssueInvalidType, value, "选项 %s 的值应为对象（输入名到值），实际为%s", key, valueTypeName(value))
+				continue
+			}
+			names := make([]string, 0, len(inputs))
+			for name := range inputs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if !hasInput(opt, name) {
+					add(key, client.OptionIssueUnknownInput, name, "选项 %s 的输入 %s 未声明", key, name)
+					continue
+				}
+				switch inputs[name].(type) {
+				case map[string]interface{}, []interface{}:
+					add(key, client.OptionIssueInvalidType, inputs[name], "选项 %s 的输入 %s 的值应为字符串、数字或布尔值，实际为%s", key, name, valueTypeName(inputs[name]))
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// hasCase 检查选项是否包含指定 case
+func hasCase(opt *OptionConfig, name string) bool {
+	for _, c := range opt.Cases {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasInput 检查输入选项是否声明了指定输入
+func hasInput(opt *OptionConfig, name string) bool {
+	for _, input := range opt.Inputs {
+		if input.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// valueTypeName JSON 值类型的中文名称
+func valueTypeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "字符串"
+	case bool:
+		return "布尔值"
+	case float64, float32, int, int64:
+		return "数字"
+	case []interface{}, []string:
+		return "数组"
+	case map[string]interface{}, map[string]string:
+		return "对象"
+	default:
+		return fmt.Sprintf(" %T", v)
+	}
+}
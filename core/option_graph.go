@@ -0,0 +1,132 @@
+package core
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// MaxOptionDepth case 嵌套选项的最大层数（任务直接引用的选项为第 1 层）
+const MaxOptionDepth = 16
+
+var (
+	// ErrOptionCycle 选项通过 case 嵌套引用了自身
+	ErrOptionCycle = errors.New("选项循环引用")
+	// ErrOptionTooDeep 选项嵌套层数超过 MaxOptionDepth
+	ErrOptionTooDeep = errors.New("选项嵌套层数过多")
+)
+
+// OptionGraph 选项依赖图：选项 -> 其各个 case 中嵌套的选项
+type OptionGraph struct {
+	edges  map[string][]string
+	cycles [][]string
+	depths map[string]int
+}
+
+// buildOptionGraph 根据当前选项定义构建依赖图（LoadInterface 完成导入后调用）
+func buildOptionGraph(options map[string]*OptionConfig) *OptionGraph {
+	g := &OptionGraph{
+		edges:  make(map[string][]string),
+		depths: make(map[string]int),
+	}
+
+	names := make([]string, 0, len(options))
+	for name, opt := range options {
+		if opt == nil {
+			continue
+		}
+		names = append(names, name)
+		seen := make(map[string]bool)
+		for _, c := range opt.Cases {
+			for _, nested := range c.Option {
+				if !seen[nested] {
+					seen[nested] = true
+					g.edges[name] = append(g.edges[name], nested)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+
+	// 深度优先遍历，回边即为循环
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	var visit func(name string) int
+	visit = func(name string) int {
+		switch state[name] {
+		case visiting:
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == name {
+					cycle := append(append([]string(nil), stack[i:]...), name)
+					g.cycles = append(g.cycles, cycle)
+					break
+				}
+			}
+			return 0
+		case done:
+			return g.depths[name]
+		}
+
+		state[name] = visiting
+		stack = append(stack, name)
+		depth := 1
+		for _, nested := range g.edges[name] {
+			if _, ok := options[nested]; !ok {
+				continue
+			}
+			if d := visit(nested) + 1; d > depth {
+				depth = d
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		g.depths[name] = depth
+		return depth
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return g
+}
+
+// Dependencies 获取选项各 case 中直接嵌套的选项（按出现顺序，去重）
+func (g *OptionGraph) Dependencies(name string) []string {
+	return g.edges[name]
+}
+
+// Edges 获取所有存在嵌套选项的选项及其依赖
+func (g *OptionGraph) Edges() map[string][]string {
+	edges := make(map[string][]string, len(g.edges))
+	for name, deps := range g.edges {
+		edges[name] = append([]string(nil), deps...)
+	}
+	return edges
+}
+
+// Cycles 获取检测到的循环引用路径，如 [A B A]
+func (g *OptionGraph) Cycles() [][]string {
+	return g.cycles
+}
+
+// Depth 获取从该选项开始的最大嵌套层数（不含嵌套选项时为 1，忽略循环）
+func (g *OptionGraph) Depth(name string) int {
+	return g.depths[name]
+}
+
+// OptionGraph 获取加载时构建的选项依赖图
+func (pi *ProjectInterface) OptionGraph() *OptionGraph {
+	if pi.optionGraph == nil {
+		pi.optionGraph = buildOptionGraph(pi.Options)
+	}
+	return pi.optionGraph
+}
+
+// formatOptionPath 格式化选项路径，如 A -> B -> A
+func formatOptionPath(path []string) string {
+	return strings.Join(path, " -> ")
+}
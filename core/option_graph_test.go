@@ -0,0 +1,75 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOptionGraph(t *testing.T) {
+	// Chain0 -> Chain1 -> ... -> Chain17，共 18 层
+	chain := ""
+	for i := 0; i < 18; i++ {
+		nested := ""
+		if i < 17 {
+			nested = fmt.Sprintf(`, "option": ["Chain%d"]`, i+1)
+		}
+		chain += fmt.Sprintf(`, "Chain%d": {"type": "switch", "cases": [{"name": "Yes"%s}]}`, i, nested)
+	}
+	dir := t.TempDir()
+	writeFile(t, dir, "interface.json", `{
+		"task": [
+			{"name": "Loop", "entry": "Loop", "option": ["A"]},
+			{"name": "Deep", "entry": "Deep", "option": ["Chain0"]}
+		],
+		"option": {
+			"A": {"type": "select", "cases": [{"name": "X", "option": ["B", "Leaf"]}, {"name": "Y", "option": ["Leaf"]}]},
+			"B": {"type": "switch", "cases": [{"name": "Yes", "option": ["A"]}]},
+			"Leaf": {"type": "switch", "cases": [{"name": "Yes"}]}`+chain+`
+		}
+	}`)
+	pi, err := LoadInterface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph := pi.OptionGraph()
+	if deps := graph.Dependencies("A"); !reflect.DeepEqual(deps, []string{"B", "Leaf"}) {
+		t.Errorf("Dependencies(A) = %v", deps)
+	}
+	if cycles := graph.Cycles(); !reflect.DeepEqual(cycles, [][]string{{"A", "B", "A"}}) {
+		t.Errorf("Cycles() = %v", cycles)
+	}
+	if depth := graph.Depth("Chain0"); depth != 18 {
+		t.Errorf("Depth(Chain0) = %d", depth)
+	}
+
+	var codes []string
+	for _, d := range pi.Validate() {
+		codes = append(codes, d.Code+" "+d.Path)
+	}
+	want := []string{
+		DiagOptionCycle + " $.option.A.cases",
+		DiagOptionTooDeep + " $.task[1].option[0]",
+	}
+	if !reflect.DeepEqual(codes, want) {
+		t.Errorf("Validate() = %v, want %v", codes, want)
+	}
+
+	resolver := NewOptionResolver(pi)
+	_, err = resolver.ResolveTaskOptions("Loop", map[string]interface{}{"A": "X"})
+	if !errors.Is(err, ErrOptionCycle) || !strings.Contains(err.Error(), "A -> B -> A") {
+		t.Errorf("Loop: err = %v", err)
+	}
+	_, err = resolver.ResolveTaskOptions("Deep", nil)
+	if !errors.Is(err, ErrOptionTooDeep) || !strings.Contains(err.Error(), "Chain15 -> Chain16") {
+		t.Errorf("Deep: err = %v", err)
+	}
+
+	// 出错后解析器状态应被重置
+	if _, err := resolver.ResolveTaskOptions("Loop", map[string]interface{}{"A": "Y"}); err != nil {
+		t.Errorf("Loop(Y): err = %v", err)
+	}
+}
@@ -1,20 +1,60 @@
 package core
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+
+	"maaend-client/client"
 )
 
 // OptionResolver 选项解析器
 type OptionResolver struct {
-	pi *ProjectInterface
+	pi      *ProjectInterface
+	verifys map[string]*regexp.Regexp // 已编译的 verify 正则
+	tracer  *optionTracer             // 仅 ExplainTaskOptions 期间非空
+	stack   []string                  // 正在解析的选项路径，用于检测循环引用和限制深度
 }
 
 // NewOptionResolver 创建选项解析器
 func NewOptionResolver(pi *ProjectInterface) *OptionResolver {
-	return &OptionResolver{pi: pi}
+	return &OptionResolver{pi: pi, verifys: make(map[string]*regexp.Regexp)}
+}
+
+// InputError 输入选项的值未通过校验或无法转换为 pipeline_type 指定的类型
+type InputError struct {
+	Task   string
+	Option string
+	Input  string
+	Value  string
+	Reason string
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("选项 %s 的输入 %s 的值 %q 无效: %s", e.Option, e.Input, e.Value, e.Reason)
+}
+
+// InvalidInput 转换为上报服务器的结构化信息
+func (e *InputError) InvalidInput() *client.InvalidInputInfo {
+	return &client.InvalidInputInfo{
+		Task:   e.Task,
+		Option: e.Option,
+		Input:  e.Input,
+		Value:  e.Value,
+		Reason: e.Reason,
+	}
+}
+
+// ExplainTaskOptions 解析任务选项，同时返回按顺序记录的每个选项、选中的 case 及原因、
+// 访问的嵌套选项和被覆盖的键。解析失败时返回失败前的追踪记录和错误
+func (r *OptionResolver) ExplainTaskOptions(taskName string, userOptions map[string]interface{}) (*client.OptionExplanation, error) {
+	r.tracer = newOptionTracer()
+	defer func() { r.tracer = nil }()
+
+	override, err := r.ResolveTaskOptions(taskName, userOptions)
+	return &client.OptionExplanation{Override: override, Trace: r.tracer.result()}, err
 }
 
 // ResolveTaskOptions 解析任务选项，构建 pipeline_override
@@ -30,12 +70,14 @@ func (r *OptionResolver) ResolveTaskOptions(taskName string, userOptions map[str
 	// 首先应用任务级别的 pipeline_override
 	if task.PipelineOverride != nil {
 		mergeOverride(override, task.PipelineOverride)
+		r.tracer.record(client.OptionTraceStep{Step: client.TraceStepTask}, task.PipelineOverride)
 	}
 
 	// 处理每个 option
 	for _, optName := range task.Option {
 		opt := r.pi.GetOption(optName)
 		if opt == nil {
+			r.tracer.skip(optName, "", "选项未定义")
 			continue
 		}
 
@@ -45,6 +87,10 @@ func (r *OptionResolver) ResolveTaskOptions(taskName string, userOptions map[str
 		// 解析选项
 		optOverride, err := r.resolveOption(optName, opt, userValue, userOptions)
 		if err != nil {
+			var inputErr *InputError
+			if errors.As(err, &inputErr) {
+				inputErr.Task = taskName
+			}
 			return nil, fmt.Errorf("解析选项 %s 失败: %w", optName, err)
 		}
 
@@ -58,6 +104,26 @@ func (r *OptionResolver) ResolveTaskOptions(taskName string, userOptions map[str
 
 // resolveOption 解析单个选项
 func (r *OptionResolver) resolveOption(name string, opt *OptionConfig, userValue interface{}, allUserOptions map[string]interface{}) (map[string]interface{}, error) {
+	path := append(append([]string(nil), r.stack...), name)
+	for _, ancestor := range r.stack {
+		if ancestor == name {
+			return nil, fmt.Errorf("%w: %s", ErrOptionCycle, formatOptionPath(path))
+		}
+	}
+	if len(path) > MaxOptionDepth {
+		return nil, fmt.Errorf("%w（最多 %d 层）: %s", ErrOptionTooDeep, MaxOptionDepth, formatOptionPath(path))
+	}
+	r.stack = path
+	defer func() { r.stack = path[:len(path)-1] }()
+
+	r.tracer.begin()
+	override, err := r.resolveOptionType(name, opt, userValue, allUserOptions)
+	r.tracer.end(err == nil)
+	return override, err
+}
+
+// resolveOptionType 按选项类型解析
+func (r *OptionResolver) resolveOptionType(name string, opt *OptionConfig, userValue interface{}, allUserOptions map[string]interface{}) (map[string]interface{}, error) {
 	switch opt.Type {
 	case "select":
 		return r.resolveSelectOption(name, opt, userValue, allUserOptions)
@@ -79,19 +145,19 @@ func (r *OptionResolver) resolveSelectOption(name string, opt *OptionConfig, use
 
 	// 获取选中的 case
 	// 优先级：用户选择 > DefaultCase > Default > 第一个 case
-	selectedCase := opt.DefaultCase
+	selectedCase, reason := opt.DefaultCase, client.CaseReasonDefaultCase
 	if selectedCase == "" && opt.Default != "" {
-		selectedCase = opt.Default
+		selectedCase, reason = opt.Default, client.CaseReasonDefault
 	}
 	if userValue != nil {
 		if strVal, ok := userValue.(string); ok {
-			selectedCase = strVal
+			selectedCase, reason = strVal, client.CaseReasonUser
 		}
 	}
 
 	// 如果仍然为空，使用第一个 case 作为默认
 	if selectedCase == "" && len(opt.Cases) > 0 {
-		selectedCase = opt.Cases[0].Name
+		selectedCase, reason = opt.Cases[0].Name, client.CaseReasonFirst
 	}
 
 	// 查找对应的 case
@@ -106,6 +172,7 @@ func (r *OptionResolver) resolveSelectOption(name string, opt *OptionConfig, use
 	if caseConfig == nil {
 		// 如果没有找到且有 cases，返回空 override 而非错误
 		if len(opt.Cases) == 0 {
+			r.tracer.skip(name, "", "选项没有 case")
 			return override, nil
 		}
 		return nil, fmt.Errorf("选项 %s 的 case %s 不存在", name, selectedCase)
@@ -115,34 +182,53 @@ func (r *OptionResolver) resolveSelectOption(name string, opt *OptionConfig, use
 	if caseConfig.PipelineOverride != nil {
 		mergeOverride(override, caseConfig.PipelineOverride)
 	}
+	r.tracer.record(client.OptionTraceStep{
+		Step:   client.TraceStepCase,
+		Option: name,
+		Case:   caseConfig.Name,
+		Reason: reason,
+	}, caseConfig.PipelineOverride)
 
 	// 递归处理嵌套选项
+	if err := r.resolveNestedOptions(name, caseConfig, override, allUserOptions); err != nil {
+		return nil, err
+	}
+
+	return override, nil
+}
+
+// resolveNestedOptions 解析 case 下的嵌套选项并合并到 override
+func (r *OptionResolver) resolveNestedOptions(name string, caseConfig *CaseConfig, override map[string]interface{}, allUserOptions map[string]interface{}) error {
+	r.tracer.enter(name, caseConfig.Name)
+	defer r.tracer.leave()
+
 	for _, nestedOptName := range caseConfig.Option {
 		nestedOpt := r.pi.GetOption(nestedOptName)
 		if nestedOpt == nil {
+			r.tracer.skip(nestedOptName, "", "选项未定义")
 			continue
 		}
 
 		nestedValue := allUserOptions[nestedOptName]
 		nestedOverride, err := r.resolveOption(nestedOptName, nestedOpt, nestedValue, allUserOptions)
 		if err != nil {
-			return nil, fmt.Errorf("解析嵌套选项 %s 失败: %w", nestedOptName, err)
+			return fmt.Errorf("解析嵌套选项 %s 失败: %w", nestedOptName, err)
 		}
 
 		if nestedOverride != nil {
 			mergeOverride(override, nestedOverride)
 		}
 	}
-
-	return override, nil
+	return nil
 }
 
 // resolveCheckboxOption 解析复选框类型选项
-func (r *OptionResolver) resolveCheckboxOption(_ string, opt *OptionConfig, userValue interface{}, allUserOptions map[string]interface{}) (map[string]interface{}, error) {
+func (r *OptionResolver) resolveCheckboxOption(name string, opt *OptionConfig, userValue interface{}, allUserOptions map[string]interface{}) (map[string]interface{}, error) {
 	override := make(map[string]interface{})
 
 	// 获取选中的 cases
 	var selectedCases []string
+	reason := client.CaseReasonUser
 	if userValue != nil {
 		switch v := userValue.(type) {
 		case []interface{}:
@@ -159,6 +245,10 @@ func (r *OptionResolver) resolveCheckboxOption(_ string, opt *OptionConfig, user
 	// 如果没有选择，使用默认值
 	if len(selectedCases) == 0 && opt.DefaultCase != "" {
 		selectedCases = strings.Split(opt.DefaultCase, ",")
+		reason = client.CaseReasonDefaultCase
+	}
+	if len(selectedCases) == 0 {
+		r.tracer.skip(name, "", "未选中任何 case")
 	}
 
 	// 处理每个选中的 case
@@ -173,6 +263,7 @@ func (r *OptionResolver) resolveCheckboxOption(_ string, opt *OptionConfig, user
 		}
 
 		if caseConfig == nil {
+			r.tracer.skip(name, caseName, "case 不存在")
 			continue
 		}
 
@@ -180,23 +271,16 @@ func (r *OptionResolver) resolveCheckboxOption(_ string, opt *OptionConfig, user
 		if caseConfig.PipelineOverride != nil {
 			mergeOverride(override, caseConfig.PipelineOverride)
 		}
+		r.tracer.record(client.OptionTraceStep{
+			Step:   client.TraceStepCase,
+			Option: name,
+			Case:   caseConfig.Name,
+			Reason: reason,
+		}, caseConfig.PipelineOverride)
 
 		// 递归处理嵌套选项
-		for _, nestedOptName := range caseConfig.Option {
-			nestedOpt := r.pi.GetOption(nestedOptName)
-			if nestedOpt == nil {
-				continue
-			}
-
-			nestedValue := allUserOptions[nestedOptName]
-			nestedOverride, err := r.resolveOption(nestedOptName, nestedOpt, nestedValue, allUserOptions)
-			if err != nil {
-				return nil, fmt.Errorf("解析嵌套选项 %s 失败: %w", nestedOptName, err)
-			}
-
-			if nestedOverride != nil {
-				mergeOverride(override, nestedOverride)
-			}
+		if err := r.resolveNestedOptions(name, caseConfig, override, allUserOptions); err != nil {
+			return nil, err
 		}
 	}
 
@@ -204,7 +288,7 @@ func (r *OptionResolver) resolveCheckboxOption(_ string, opt *OptionConfig, user
 }
 
 // resolveInputOption 解析输入类型选项
-func (r *OptionResolver) resolveInputOption(_ string, opt *OptionConfig, userValue interface{}, _ map[string]interface{}) (map[string]interface{}, error) {
+func (r *OptionResolver) resolveInputOption(name string, opt *OptionConfig, userValue interface{}, allUserOptions map[string]interface{}) (map[string]interface{}, error) {
 	override := make(map[string]interface{})
 
 	// 获取输入值
@@ -218,81 +302,645 @@ func (r *OptionResolver) resolveInputOption(_ string, opt *OptionConfig, userVal
 		}
 	}
 
-	// 覆盖用户输入
+	// 覆盖用户输入，并使用 verify 校验
+	userInputs := make(map[string]string)
 	if userValue != nil {
 		switch v := userValue.(type) {
 		case map[string]interface{}:
 			for k, val := range v {
-				inputValues[k] = formatInputValue(val)
+				userInputs[k] = formatInputValue(val)
 			}
 		case map[string]string:
 			for k, val := range v {
-				inputValues[k] = val
+				userInputs[k] = val
 			}
 		}
 	}
+	for i := range opt.Inputs {
+		input := &opt.Inputs[i]
+		val, ok := userInputs[input.Name]
+		if !ok {
+			continue
+		}
+		if err := r.verifyInput(name, input, val); err != nil {
+			return nil, err
+		}
+	}
+	for k, val := range userInputs {
+		inputValues[k] = val
+	}
+
+	// 按 pipeline_type 转换类型，用于占位符即整个值的情况
+	typedValues := make(map[string]interface{})
+	for i := range opt.Inputs {
+		input := &opt.Inputs[i]
+		val, ok := inputValues[input.Name]
+		if !ok {
+			continue
+		}
+		typed, err := convertInputValue(val, input.PipelineType)
+		if err != nil {
+			return nil, &InputError{Option: name, Input: input.Name, Value: val, Reason: err.Error()}
+		}
+		typedValues[input.Name] = typed
+	}
 
 	// 应用 pipeline_override 并进行变量替换
+	var resolved map[string]interface{}
 	if opt.PipelineOverride != nil {
-		resolved := resolveVariables(opt.PipelineOverride, inputValues)
+		scope := &templateScope{
+			r:           r,
+			option:      name,
+			opt:         opt,
+			values:      inputValues,
+			typed:       typedValues,
+			userOptions: allUserOptions,
+		}
+		var err error
+		if resolved, err = resolveVariables(opt.PipelineOverride, scope); err != nil {
+			var inputErr *InputError
+			if errors.As(err, &inputErr) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("选项 %s 的 pipeline_override 模板错误: %w", name, err)
+		}
 		mergeOverride(override, resolved)
 	}
+	r.tracer.record(client.OptionTraceStep{Step: client.TraceStepInput, Option: name, Inputs: typedValues}, resolved)
 
 	return override, nil
 }
 
-// resolveVariables 替换 pipeline_override 中的变量
-func resolveVariables(override map[string]interface{}, values map[string]string) map[string]interface{} {
-	// 深拷贝
-	data, _ := json.Marshal(override)
-	var result map[string]interface{}
-	json.Unmarshal(data, &result)
+// verifyInput 使用 verify 正则校验用户输入
+func (r *OptionResolver) verifyInput(option string, input *InputConfig, value string) error {
+	if input.Verify == "" {
+		return nil
+	}
 
-	// 递归替换
-	resolveVariablesRecursive(result, values)
+	re, ok := r.verifys[input.Verify]
+	if !ok {
+		var err error
+		re, err = regexp.Compile(input.Verify)
+		if err != nil {
+			return fmt.Errorf("选项 %s 的输入 %s 的 verify 正则无效: %w", option, input.Name, err)
+		}
+		r.verifys[input.Verify] = re
+	}
 
-	return result
+	if !re.MatchString(value) {
+		return &InputError{
+			Option: option,
+			Input:  input.Name,
+			Value:  value,
+			Reason: fmt.Sprintf("不符合校验规则 %s", input.Verify),
+		}
+	}
+	return nil
+}
+
+// isPipelineType 检查是否为支持的 pipeline_type（为空视为 string）
+func isPipelineType(t string) bool {
+	switch t {
+	case "", "string", "int", "double", "bool":
+		return true
+	}
+	return false
+}
+
+// convertInputValue 按 pipeline_type 转换输入值（未指定类型时保持字符串）
+func convertInputValue(value, pipelineType string) (interface{}, error) {
+	switch pipelineType {
+	case "", "string":
+		return value, nil
+	case "int":
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("不是有效的整数")
+		}
+		return n, nil
+	case "double":
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("不是有效的数字")
+		}
+		return f, nil
+	case "bool":
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("不是有效的布尔值")
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("不支持的 pipeline_type: %s", pipelineType)
+	}
+}
+
+// templateScope 输入选项 pipeline_override 模板的求值上下文
+//
+// 占位符语法（{{ 和 }} 表示字面量花括号）：
+//
+//	{name}            输入值
+//	{name:fallback}   输入没有值时使用 fallback
+//	{@Option}         其他选项选中的 case（checkbox 为逗号分隔的多个 case）
+//	{@Option.input}   其他输入选项的输入值
+//	{count*2+1}       四则运算（+ - * / 和括号），操作数为数字、输入或选项引用
+//	{count|%03d}      按 fmt 格式输出（结果为字符串）
+//
+// 引用未知名称或无法解析的占位符原样保留（如正则中的 {3}、{1,2}），
+// 引用已声明但没有值的输入时返回 InputError
+type templateScope struct {
+	r           *OptionResolver
+	option      string
+	opt         *OptionConfig
+	values      map[string]string      // 输入值
+	typed       map[string]interface{} // 按 pipeline_type 转换后的输入值
+	userOptions map[string]interface{}
+}
+
+// errNotTemplate 占位符不是模板（引用未知名称或语法不符），应原样保留
+var errNotTemplate = errors.New("not a template")
+
+// resolveVariables 替换 pipeline_override 中的变量
+// 字符串恰好为单个占位符时替换为类型化的值，否则按字符串插值
+func resolveVariables(override map[string]interface{}, scope *templateScope) (map[string]interface{}, error) {
+	result, _ := cloneValue(override).(map[string]interface{})
+	if err := resolveVariablesRecursive(result, scope); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 // resolveVariablesRecursive 递归替换变量
-func resolveVariablesRecursive(data interface{}, values map[string]string) {
+func resolveVariablesRecursive(data interface{}, scope *templateScope) error {
 	switch v := data.(type) {
 	case map[string]interface{}:
 		for key, val := range v {
-			switch vv := val.(type) {
-			case string:
-				v[key] = replaceVariables(vv, values)
-			case map[string]interface{}:
-				resolveVariablesRecursive(vv, values)
-			case []interface{}:
-				resolveVariablesRecursive(vv, values)
+			resolved, err := resolveTemplateValue(val, scope)
+			if err != nil {
+				return err
 			}
+			v[key] = resolved
 		}
 	case []interface{}:
 		for i, item := range v {
-			switch vv := item.(type) {
-			case string:
-				v[i] = replaceVariables(vv, values)
-			case map[string]interface{}:
-				resolveVariablesRecursive(vv, values)
-			case []interface{}:
-				resolveVariablesRecursive(vv, values)
+			resolved, err := resolveTemplateValue(item, scope)
+			if err != nil {
+				return err
 			}
+			v[i] = resolved
 		}
 	}
+	return nil
+}
+
+// resolveTemplateValue 替换单个 JSON 值中的变量
+func resolveTemplateValue(val interface{}, scope *templateScope) (interface{}, error) {
+	switch vv := val.(type) {
+	case string:
+		return substituteValue(vv, scope)
+	case map[string]interface{}, []interface{}:
+		return val, resolveVariablesRecursive(vv, scope)
+	}
+	return val, nil
 }
 
-// replaceVariables 替换字符串中的变量
-func replaceVariables(s string, values map[string]string) string {
-	// 匹配 {varName} 格式
-	re := regexp.MustCompile(`\{(\w+)\}`)
-	return re.ReplaceAllStringFunc(s, func(match string) string {
-		varName := match[1 : len(match)-1]
-		if val, ok := values[varName]; ok {
-			return val
+// substituteValue 替换单个字符串值中的变量
+func substituteValue(s string, scope *templateScope) (interface{}, error) {
+	// 整个字符串为单个占位符时保留类型
+	if len(s) > 2 && s[0] == '{' && s[1] != '{' && strings.IndexByte(s, '}') == len(s)-1 {
+		val, err := scope.evaluate(s[1 : len(s)-1])
+		if err == nil {
+			return val, nil
 		}
-		return match
-	})
+		if err != errNotTemplate {
+			return nil, err
+		}
+	}
+	return replaceVariables(s, scope)
+}
+
+// replaceVariables 替换字符串中的变量并处理 {{ }} 转义
+func replaceVariables(s string, scope *templateScope) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"):
+			b.WriteByte('{')
+			i += 2
+		case strings.HasPrefix(s[i:], "}}"):
+			b.WriteByte('}')
+			i += 2
+		case s[i] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String(), nil
+			}
+			body := s[i+1 : i+end]
+			val, err := scope.evaluate(body)
+			switch {
+			case err == errNotTemplate:
+				b.WriteString(s[i : i+end+1])
+			case err != nil:
+				return "", err
+			default:
+				b.WriteString(templateString(val))
+			}
+			i += end + 1
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return b.String(), nil
+}
+
+// evaluate 对占位符内容求值，不是模板时返回 errNotTemplate
+func (sc *templateScope) evaluate(body string) (interface{}, error) {
+	expr, format, hasFormat := strings.Cut(body, "|")
+	expr = strings.TrimSpace(expr)
+
+	var val interface{}
+	if ref, fallback, ok := strings.Cut(expr, ":"); ok {
+		ref = strings.TrimSpace(ref)
+		if !isTemplateRef(ref) {
+			return nil, errNotTemplate
+		}
+		v, found, err := sc.lookup(ref, true)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			if !sc.isDeclared(ref) {
+				return nil, errNotTemplate
+			}
+			if v, err = sc.fallback(ref, fallback); err != nil {
+				return nil, err
+			}
+		}
+		val = v
+	} else {
+		p := &templateParser{scope: sc, src: expr}
+		v, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		if p.refs == 0 {
+			// 不引用任何变量的表达式（如 {3}）不是模板
+			return nil, errNotTemplate
+		}
+		val = v
+	}
+
+	if hasFormat {
+		return formatTemplateValue(strings.TrimSpace(format), val)
+	}
+	return val, nil
+}
+
+// isTemplateRef 检查是否为输入名或 @选项引用
+func isTemplateRef(ref string) bool {
+	return templateRefPattern.MatchString(ref)
+}
+
+var templateRefPattern = regexp.MustCompile(`^(@\w+(\.\w+)?|[A-Za-z_]\w*)$`)
+
+// isDeclared 检查输入名是否在选项中声明
+func (sc *templateScope) isDeclared(name string) bool {
+	return hasInput(sc.opt, name)
+}
+
+// lookup 获取引用的值。输入返回类型化值；@ 引用返回选中的 case 或输入值
+// optional 为 false 时已声明但没有值的输入返回 InputError
+func (sc *templateScope) lookup(ref string, optional bool) (interface{}, bool, error) {
+	if strings.HasPrefix(ref, "@") {
+		val, err := sc.r.optionValue(ref[1:], sc.userOptions)
+		return val, err == nil, err
+	}
+	if val, ok := sc.typed[ref]; ok {
+		return val, true, nil
+	}
+	if val, ok := sc.values[ref]; ok {
+		return val, true, nil
+	}
+	if sc.isDeclared(ref) && !optional {
+		return nil, false, &InputError{Option: sc.option, Input: ref, Reason: "pipeline_override 引用了该输入，但未提供值且没有默认值"}
+	}
+	return nil, false, nil
+}
+
+// fallback 按输入的 pipeline_type 转换默认值文本
+func (sc *templateScope) fallback(name, text string) (interface{}, error) {
+	for _, input := range sc.opt.Inputs {
+		if input.Name != name {
+			continue
+		}
+		val, err := convertInputValue(text, input.PipelineType)
+		if err != nil {
+			return nil, &InputError{Option: sc.option, Input: name, Value: text, Reason: "占位符默认值" + err.Error()}
+		}
+		return val, nil
+	}
+	return text, nil
+}
+
+// optionValue 获取其他选项的选中值：select/switch 为 case 名称，checkbox 为逗号分隔的 case 名称，
+// name 为 选项.输入 时为输入选项的输入值
+func (r *OptionResolver) optionValue(name string, userOptions map[string]interface{}) (interface{}, error) {
+	optName, inputName, isInput := strings.Cut(name, ".")
+	opt := r.pi.GetOption(optName)
+	if opt == nil {
+		return nil, fmt.Errorf("占位符 {@%s} 引用的选项 %s 不存在", name, optName)
+	}
+	userValue := userOptions[optName]
+
+	if isInput || opt.Type == "input" {
+		if opt.Type != "input" || !isInput {
+			return nil, fmt.Errorf("占位符 {@%s} 无效：只有输入选项可以引用输入（@选项.输入）", name)
+		}
+		for _, input := range opt.Inputs {
+			if input.Name != inputName {
+				continue
+			}
+			if m, ok := userValue.(map[string]interface{}); ok {
+				if v, ok := m[inputName]; ok {
+					return formatInputValue(v), nil
+				}
+			}
+			return input.GetDefaultString(), nil
+		}
+		return nil, fmt.Errorf("占位符 {@%s} 引用的输入 %s 不存在", name, inputName)
+	}
+
+	switch opt.Type {
+	case "checkbox":
+		var selected []string
+		if list, ok := userValue.([]interface{}); ok {
+			for _, item := range list {
+				if str, ok := item.(string); ok {
+					selected = append(selected, str)
+				}
+			}
+		}
+		if len(selected) == 0 && opt.DefaultCase != "" {
+			for _, c := range strings.Split(opt.DefaultCase, ",") {
+				selected = append(selected, strings.TrimSpace(c))
+			}
+		}
+		return strings.Join(selected, ","), nil
+	default:
+		selected := opt.DefaultCase
+		if selected == "" {
+			selected = opt.Default
+		}
+		if str, ok := userValue.(string); ok {
+			selected = str
+		}
+		if selected == "" && len(opt.Cases) > 0 {
+			selected = opt.Cases[0].Name
+		}
+		return selected, nil
+	}
+}
+
+// templateParser 四则运算表达式解析器（递归下降）
+type templateParser struct {
+	scope *templateScope
+	src   string
+	pos   int
+	refs  int // 引用的变量数量
+}
+
+func (p *templateParser) parse() (interface{}, error) {
+	val, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, errNotTemplate
+	}
+	return val, nil
+}
+
+func (p *templateParser) skipSpace() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// parseSum 解析 + - 运算
+func (p *templateParser) parseSum() (interface{}, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) || (p.src[p.pos] != '+' && p.src[p.pos] != '-') {
+			return left, nil
+		}
+		op := p.src[p.pos]
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseProduct 解析 * / 运算
+func (p *templateParser) parseProduct() (interface{}, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) || (p.src[p.pos] != '*' && p.src[p.pos] != '/') {
+			return left, nil
+		}
+		op := p.src[p.pos]
+		p.pos++
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseOperand 解析数字、引用、括号表达式和负号
+func (p *templateParser) parseOperand() (interface{}, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, errNotTemplate
+	}
+
+	switch c := p.src[p.pos]; {
+	case c == '(':
+		p.pos++
+		val, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != ')' {
+			return nil, errNotTemplate
+		}
+		p.pos++
+		return val, nil
+	case c == '-':
+		p.pos++
+		val, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return arithmetic('-', int64(0), val)
+	case c >= '0' && c <= '9':
+		m := templateNumberPattern.FindString(p.src[p.pos:])
+		p.pos += len(m)
+		return toTemplateNumber(m)
+	}
+
+	m := templateOperandRefPattern.FindString(p.src[p.pos:])
+	if m == "" {
+		return nil, errNotTemplate
+	}
+	p.pos += len(m)
+	val, found, err := p.scope.lookup(m, false)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errNotTemplate
+	}
+	p.refs++
+	return val, nil
+}
+
+var (
+	templateNumberPattern     = regexp.MustCompile(`^\d+(\.\d+)?`)
+	templateOperandRefPattern = regexp.MustCompile(`^(@\w+(\.\w+)?|[A-Za-z_]\w*)`)
+)
+
+// toTemplateNumber 转换为 int64 或 float64
+func toTemplateNumber(v interface{}) (interface{}, error) {
+	switch n := v.(type) {
+	case int64, float64:
+		return n, nil
+	case string:
+		s := strings.TrimSpace(n)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("%v 不是数字", v)
+}
+
+// arithmetic 计算二元运算，整数运算保持 int64，除不尽时转为 float64
+func arithmetic(op byte, a, b interface{}) (interface{}, error) {
+	x, err := toTemplateNumber(a)
+	if err != nil {
+		return nil, fmt.Errorf("无法计算: %w", err)
+	}
+	y, err := toTemplateNumber(b)
+	if err != nil {
+		return nil, fmt.Errorf("无法计算: %w", err)
+	}
+
+	xi, xInt := x.(int64)
+	yi, yInt := y.(int64)
+	if xInt && yInt {
+		switch op {
+		case '+':
+			return xi + yi, nil
+		case '-':
+			return xi - yi, nil
+		case '*':
+			return xi * yi, nil
+		case '/':
+			if yi == 0 {
+				return nil, fmt.Errorf("除数为 0")
+			}
+			if xi%yi == 0 {
+				return xi / yi, nil
+			}
+		}
+	}
+
+	xf, yf := toFloat(x), toFloat(y)
+	switch op {
+	case '+':
+		return xf + yf, nil
+	case '-':
+		return xf - yf, nil
+	case '*':
+		return xf * yf, nil
+	default:
+		if yf == 0 {
+			return nil, fmt.Errorf("除数为 0")
+		}
+		return xf / yf, nil
+	}
+}
+
+func toFloat(v interface{}) float64 {
+	if i, ok := v.(int64); ok {
+		return float64(i)
+	}
+	return v.(float64)
+}
+
+var templateFormatPattern = regexp.MustCompile(`^%[-+# 0]*\d*(\.\d+)?[dxXobeEfgGsqv]$`)
+
+// formatTemplateValue 按 fmt 格式输出值，整数动词要求值为整数，浮点动词接受任意数字
+func formatTemplateValue(format string, val interface{}) (string, error) {
+	if !templateFormatPattern.MatchString(format) {
+		return "", fmt.Errorf("占位符格式 %q 无效（支持单个 fmt 动词，如 %%03d、%%.2f）", format)
+	}
+	switch format[len(format)-1] {
+	case 'd', 'x', 'X', 'o', 'b':
+		n, err := toTemplateNumber(val)
+		if err != nil {
+			return "", fmt.Errorf("格式 %s 需要整数: %w", format, err)
+		}
+		if f, ok := n.(float64); ok {
+			if f != float64(int64(f)) {
+				return "", fmt.Errorf("格式 %s 需要整数，实际为 %v", format, f)
+			}
+			n = int64(f)
+		}
+		return fmt.Sprintf(format, n), nil
+	case 'e', 'E', 'f', 'g', 'G':
+		n, err := toTemplateNumber(val)
+		if err != nil {
+			return "", fmt.Errorf("格式 %s 需要数字: %w", format, err)
+		}
+		return fmt.Sprintf(format, toFloat(n)), nil
+	default:
+		return fmt.Sprintf(format, templateString(val)), nil
+	}
+}
+
+// templateString 插值时值的字符串形式
+func templateString(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
 }
 
 // mergeOverride 合并 pipeline_override
@@ -307,8 +955,28 @@ func mergeOverride(dst, src map[string]interface{}) {
 				}
 			}
 		}
-		// 否则直接覆盖
-		dst[key] = srcVal
+		// 否则直接覆盖（复制一份，避免后续合并修改 interface.json 中的原始配置）
+		dst[key] = cloneValue(srcVal)
+	}
+}
+
+// cloneValue 深拷贝 JSON 值中的 map 和数组
+func cloneValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = cloneValue(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = cloneValue(item)
+		}
+		return list
+	default:
+		return v
 	}
 }
 
@@ -0,0 +1,355 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"maaend-client/client"
+)
+
+const inputInterface = `{
+	"task": [{"name": "Farm", "entry": "Farm", "option": ["Repeat"]}],
+	"option": {
+		"Repeat": {
+			"type": "input",
+			"inputs": [
+				{"name": "count", "pipeline_type": "int", "verify": "^[1-9]\\d*$", "default": 3},
+				{"name": "ratio", "pipeline_type": "double", "default": "0.5"},
+				{"name": "skip", "pipeline_type": "bool", "default": false},
+				{"name": "stage", "verify": "^\\d+-\\d+$", "default": "1-7"}
+			],
+			"pipeline_override": {
+				"FarmLoop": {
+					"max_hit": "{count}",
+					"threshold": "{ratio}",
+					"enabled": "{skip}",
+					"text": "关卡 {stage} x{count}",
+					"stages": ["{stage}"]
+				}
+			}
+		}
+	}
+}`
+
+func TestResolveInputTypes(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "interface.json", inputInterface)
+	pi, err := LoadInterface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewOptionResolver(pi)
+
+	override, err := resolver.ResolveTaskOptions("Farm", map[string]interface{}{
+		"Repeat": map[string]interface{}{"count": float64(10), "skip": "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"FarmLoop": map[string]interface{}{
+			"max_hit":   int64(10),
+			"threshold": 0.5,
+			"enabled":   true,
+			"text":      "关卡 1-7 x10",
+			"stages":    []interface{}{"1-7"},
+		},
+	}
+	if !reflect.DeepEqual(override, want) {
+		t.Fatalf("override = %#v", override)
+	}
+}
+
+func TestResolveInputVerify(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "interface.json", inputInterface)
+	pi, err := LoadInterface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewOptionResolver(pi)
+
+	cases := []struct {
+		name  string
+		value map[string]interface{}
+		input string
+	}{
+		{"verify", map[string]interface{}{"count": "0"}, "count"},
+		{"verify 字符串", map[string]interface{}{"stage": "abc"}, "stage"},
+		{"类型", map[string]interface{}{"skip": "maybe"}, "skip"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := resolver.ResolveTaskOptions("Farm", map[string]interface{}{"Repeat": tc.value})
+			var inputErr *InputError
+			if !errors.As(err, &inputErr) {
+				t.Fatalf("err = %v, want InputError", err)
+			}
+			if inputErr.Task != "Farm" || inputErr.Option != "Repeat" || inputErr.Input != tc.input {
+				t.Fatalf("InputError = %+v", inputErr)
+			}
+		})
+	}
+}
+
+func TestExplainTaskOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "interface.json", `{
+		"task": [{"name": "Daily", "entry": "Daily", "option": ["Mode", "Extra"],
+			"pipeline_override": {"Start": {"enabled": true, "next": ["A"]}}}],
+		"option": {
+			"Mode": {"type": "select", "default_case": "A", "cases": [
+				{"name": "A", "option": ["Speed"], "pipeline_override": {"Start": {"next": ["B"]}}},
+				{"name": "B"}
+			]},
+			"Speed": {"type": "switch", "cases": [{"name": "Fast", "pipeline_override": {"Loop": {"max_hit": 5}}}]},
+			"Extra": {"type": "checkbox", "cases": [{"name": "X", "pipeline_override": {"Loop": {"max_hit": 1}}}]}
+		}
+	}`)
+	pi, err := LoadInterface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explanation, err := NewOptionResolver(pi).ExplainTaskOptions("Daily", map[string]interface{}{"Extra": []interface{}{"X", "Y"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type step struct {
+		Step, Option, Case, Reason string
+		Depth                      int
+		Overwritten                []string
+	}
+	var got []step
+	for _, s := range explanation.Trace {
+		st := step{Step: s.Step, Option: s.Option, Case: s.Case, Reason: s.Reason, Depth: s.Depth}
+		for _, o := range s.Overwritten {
+			st.Overwritten = append(st.Overwritten, fmt.Sprintf("%s@%d", o.Key, o.PreviousStep))
+		}
+		got = append(got, st)
+	}
+	want := []step{
+		{Step: client.TraceStepTask},
+		{Step: client.TraceStepCase, Option: "Mode", Case: "A", Reason: client.CaseReasonDefaultCase, Overwritten: []string{"Start.next@0"}},
+		{Step: client.TraceStepCase, Option: "Speed", Case: "Fast", Reason: client.CaseReasonFirst, Depth: 1},
+		{Step: client.TraceStepCase, Option: "Extra", Case: "X", Reason: client.CaseReasonUser, Overwritten: []string{"Loop.max_hit@2"}},
+		{Step: client.TraceStepSkipped, Option: "Extra", Case: "Y"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("trace = %+v\nwant  %+v", got, want)
+	}
+
+	// 合并结果不应修改 interface.json 中的原始配置
+	if next := pi.GetTask("Daily").PipelineOverride["Start"].(map[string]interface{})["next"]; !reflect.DeepEqual(next, []interface{}{"A"}) {
+		t.Fatalf("任务原始配置被修改: %v", next)
+	}
+}
+
+func TestExplainMatchesResolvedOverride(t *testing.T) {
+	// 嵌套选项把 case 设置的标量替换为对象：resolver 先在选项内部合并，再与任务级结果深度合并
+	dir := t.TempDir()
+	writeFile(t, dir, "interface.json", `{
+		"task": [{"name": "Daily", "entry": "Daily", "option": ["A"],
+			"pipeline_override": {"N": {"z": 3}}}],
+		"option": {
+			"A": {"type": "select", "cases": [{"name": "On", "option": ["B"], "pipeline_override": {"N": 7}}]},
+			"B": {"type": "switch", "cases": [{"name": "Yes", "pipeline_override": {"N": {"y": 2}}}]}
+		}
+	}`)
+	pi, err := LoadInterface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewOptionResolver(pi)
+	r.tracer = newOptionTracer()
+	override, err := r.ResolveTaskOptions("Daily", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"N": map[string]interface{}{"z": float64(3), "y": float64(2)}}
+	if !reflect.DeepEqual(override, want) {
+		t.Fatalf("override = %v", override)
+	}
+	if !reflect.DeepEqual(r.tracer.applied(), override) {
+		t.Fatalf("trace applied = %v, override = %v", r.tracer.applied(), override)
+	}
+
+	steps := r.tracer.result()
+	if len(steps) != 3 {
+		t.Fatalf("steps = %+v", steps)
+	}
+	if len(steps[1].Overwritten) != 0 {
+		t.Errorf("A/On 不应覆盖任务级的键: %+v", steps[1].Overwritten)
+	}
+	if o := steps[2].Overwritten; len(o) != 1 || o[0].Key != "N" || o[0].PreviousStep != 1 {
+		t.Errorf("B/Yes 应只覆盖 A/On 写入的 N: %+v", o)
+	}
+	if !reflect.DeepEqual(steps[2].Keys, []string{"N.y"}) {
+		t.Errorf("B/Yes keys = %v", steps[2].Keys)
+	}
+}
+
+func TestCheckTaskOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "interface.json", `{
+		"task": [{"name": "Daily", "entry": "Daily", "option": ["Mode", "Extra", "Repeat"]}],
+		"option": {
+			"Mode": {"type": "select", "cases": [{"name": "A", "option": ["Speed"]}, {"name": "B"}]},
+			"Speed": {"type": "switch", "cases": [{"name": "Yes"}, {"name": "No"}]},
+			"Extra": {"type": "checkbox", "cases": [{"name": "X"}, {"name": "Y"}]},
+			"Repeat": {"type": "input", "inputs": [{"name": "count"}]},
+			"Other": {"type": "switch", "cases": [{"name": "Yes"}]}
+		}
+	}`)
+	pi, err := LoadInterface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewOptionResolver(pi)
+
+	if names := pi.TaskOptionNames("Daily"); !reflect.DeepEqual(names, []string{"Mode", "Speed", "Extra", "Repeat"}) {
+		t.Fatalf("TaskOptionNames = %v", names)
+	}
+
+	valid := map[string]interface{}{
+		"Mode":   "A",
+		"Speed":  "No",
+		"Extra":  []interface{}{"X", "Y"},
+		"Repeat": map[string]interface{}{"count": float64(3)},
+	}
+	if issues := resolver.CheckTaskOptions("Daily", valid); len(issues) != 0 {
+		t.Fatalf("合法选项报告了问题: %+v", issues)
+	}
+
+	issues := resolver.CheckTaskOptions("Daily", map[string]interface{}{
+		"Mode":   float64(1),
+		"Speed":  "Maybe",
+		"Extra":  []interface{}{"X", "Z", true},
+		"Repeat": map[string]interface{}{"count": "3", "times": "2"},
+		"Other":  "Yes",
+		"Typo":   "A",
+	})
+	var got []string
+	for _, issue := range issues {
+		if issue.Task != "Daily" {
+			t.Fatalf("issue.Task = %q", issue.Task)
+		}
+		got = append(got, fmt.Sprintf("%s:%s:%v", issue.Option, issue.Code, issue.Value))
+	}
+	want := []string{
+		"Extra:unknown_case:Z",
+		"Extra:invalid_type:true",
+		"Mode:invalid_type:1",
+		"Other:unknown_option:<nil>",
+		"Repeat:unknown_input:times",
+		"Speed:unknown_case:Maybe",
+		"Typo:unknown_option:<nil>",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("issues = %v\nwant     %v", got, want)
+	}
+
+	var optErr interface{ OptionIssues() []client.OptionIssue }
+	if !errors.As(fmt.Errorf("wrap: %w", &OptionError{Issues: issues}), &optErr) || len(optErr.OptionIssues()) != len(want) {
+		t.Fatal("OptionError 未实现 OptionIssues")
+	}
+}
+
+func TestResolveTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "interface.json", `{
+		"task": [{"name": "Farm", "entry": "Farm", "option": ["Mode", "Extra", "Level", "Tpl"]}],
+		"option": {
+			"Mode": {"type": "select", "default_case": "Hard", "cases": [{"name": "Easy"}, {"name": "Hard"}]},
+			"Extra": {"type": "checkbox", "default_case": "X,Y", "cases": [{"name": "X"}, {"name": "Y"}]},
+			"Level": {"type": "input", "inputs": [{"name": "min", "default": "5"}]},
+			"Tpl": {
+				"type": "input",
+				"inputs": [
+					{"name": "count", "pipeline_type": "int", "default": 3},
+					{"name": "ratio", "pipeline_type": "double", "default": "0.5"},
+					{"name": "stage", "default": "1-7"},
+					{"name": "note"},
+					{"name": "limit", "pipeline_type": "int"}
+				]
+			}
+		}
+	}`)
+	pi, err := LoadInterface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		options  map[string]interface{}
+		want     interface{}
+		wantErr  string
+	}{
+		{name: "整值保留类型", template: "{count}", want: int64(3)},
+		{name: "插值", template: "关卡 {stage} x{count}", want: "关卡 1-7 x3"},
+		{name: "用户输入", template: "{count}", options: map[string]interface{}{"Tpl": map[string]interface{}{"count": float64(8)}}, want: int64(8)},
+		{name: "默认值未使用", template: "{stage:0-0}", want: "1-7"},
+		{name: "默认值", template: "{note:无}", want: "无"},
+		{name: "默认值按类型转换", template: "{limit:10}", want: int64(10)},
+		{name: "默认值类型错误", template: "{limit:abc}", wantErr: "占位符默认值"},
+		{name: "未提供的输入", template: "备注 {note}", wantErr: "未提供值"},
+		{name: "未知名称原样保留", template: `\d{3}{1,2}{unknown}`, want: `\d{3}{1,2}{unknown}`},
+		{name: "转义", template: "{{count}} = {count}}}", want: "{count} = 3}"},
+		{name: "select 选中值", template: "{@Mode}", want: "Hard"},
+		{name: "select 用户选择", template: "模式 {@Mode}", options: map[string]interface{}{"Mode": "Easy"}, want: "模式 Easy"},
+		{name: "checkbox 选中值", template: "{@Extra}", want: "X,Y"},
+		{name: "其他输入选项", template: "{@Level.min}", want: "5"},
+		{name: "引用不存在的选项", template: "{@Missing}", wantErr: "不存在"},
+		{name: "输入选项缺少输入名", template: "{@Level}", wantErr: "@选项.输入"},
+		{name: "四则运算", template: "{count*2+1}", want: int64(7)},
+		{name: "运算优先级和括号", template: "{(count+1)*@Level.min}", want: int64(20)},
+		{name: "整除保持整数", template: "{count*4/2}", want: int64(6)},
+		{name: "除不尽转为小数", template: "{count/2}", want: 1.5},
+		{name: "小数运算", template: "{ratio*count}", want: 1.5},
+		{name: "负号", template: "{-count}", want: int64(-3)},
+		{name: "除数为 0", template: "{count/0}", wantErr: "除数为 0"},
+		{name: "非数字运算", template: "{stage+1}", wantErr: "不是数字"},
+		{name: "格式化", template: "{count|%03d}", want: "003"},
+		{name: "格式化运算结果", template: "第{count*2|%02d}次 {ratio|%.2f}", want: "第06次 0.50"},
+		{name: "格式化需要整数", template: "{ratio|%d}", wantErr: "需要整数"},
+		{name: "无效格式", template: "{count|%d%d}", wantErr: "无效"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pi.GetOption("Tpl").PipelineOverride = map[string]interface{}{
+				"Node": map[string]interface{}{"value": tt.template},
+			}
+			override, err := NewOptionResolver(pi).ResolveTaskOptions("Farm", tt.options)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want 包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := override["Node"].(map[string]interface{})["value"]
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	// 未提供值的输入报告为 InputError
+	pi.GetOption("Tpl").PipelineOverride = map[string]interface{}{"Node": map[string]interface{}{"value": "{note}"}}
+	_, err = NewOptionResolver(pi).ResolveTaskOptions("Farm", nil)
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) || inputErr.Task != "Farm" || inputErr.Input != "note" {
+		t.Fatalf("err = %v, want InputError", err)
+	}
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"sort"
+	"strings"
+
+	"maaend-client/client"
+)
+
+// optionTracer 记录选项解析过程（仅在 ExplainTaskOptions 期间启用）
+// 方法在 nil 接收者上为空操作
+//
+// 合并顺序与 OptionResolver 一致：每个选项先合并到自己的局部 override（case 及其嵌套选项），
+// 解析结束后再整体合并到上一层，因此覆盖检测和最终结果与实际的 pipeline_override 相同
+type optionTracer struct {
+	steps   []client.OptionTraceStep
+	scopes  []*traceScope // scopes[0] 为任务的合并结果，其后为正在解析的选项的局部 override
+	parents []string      // 当前嵌套路径（选项/case）
+}
+
+// traceScope 一层合并结果及其中每个键的写入步骤
+type traceScope struct {
+	values map[string]interface{}
+	owners map[string]int // 键路径 -> 最后写入的步骤下标
+}
+
+func newOptionTracer() *optionTracer {
+	return &optionTracer{
+		steps:  []client.OptionTraceStep{},
+		scopes: []*traceScope{newTraceScope()},
+	}
+}
+
+func newTraceScope() *traceScope {
+	return &traceScope{
+		values: make(map[string]interface{}),
+		owners: make(map[string]int),
+	}
+}
+
+// applied 按步骤合并的最终结果，与解析出的 pipeline_override 相同
+func (t *optionTracer) applied() map[string]interface{} {
+	return t.scopes[0].values
+}
+
+// result 返回追踪步骤，每个步骤的键和覆盖记录按键排序
+func (t *optionTracer) result() []client.OptionTraceStep {
+	for i := range t.steps {
+		sort.Strings(t.steps[i].Keys)
+		overwritten := t.steps[i].Overwritten
+		sort.Slice(overwritten, func(a, b int) bool { return overwritten[a].Key < overwritten[b].Key })
+	}
+	return t.steps
+}
+
+// record 记录一个步骤，并按 mergeOverride 的规则把 override 合并到当前选项的局部结果
+func (t *optionTracer) record(step client.OptionTraceStep, override map[string]interface{}) {
+	if t == nil {
+		return
+	}
+	step.Depth = len(t.parents)
+	if step.Depth > 0 {
+		step.Parent = t.parents[step.Depth-1]
+	}
+	t.steps = append(t.steps, step)
+	scope := t.scopes[len(t.scopes)-1]
+	t.merge(scope, scope.values, override, "", nil, len(t.steps)-1)
+}
+
+// skip 记录未生效的选项或 case
+func (t *optionTracer) skip(option, caseName, message string) {
+	t.record(client.OptionTraceStep{Step: client.TraceStepSkipped, Option: option, Case: caseName, Message: message}, nil)
+}
+
+// begin 开始解析一个选项，对应 resolver 中该选项的局部 override
+func (t *optionTracer) begin() {
+	if t == nil {
+		return
+	}
+	t.scopes = append(t.scopes, newTraceScope())
+}
+
+// end 选项解析结束，局部结果合并到上一层（与 resolver 合并 optOverride 的时机相同）；解析失败时丢弃
+func (t *optionTracer) end(ok bool) {
+	if t == nil {
+		return
+	}
+	scope := t.scopes[len(t.scopes)-1]
+	t.scopes = t.scopes[:len(t.scopes)-1]
+	if ok {
+		parent := t.scopes[len(t.scopes)-1]
+		t.merge(parent, parent.values, scope.values, "", scope, -1)
+	}
+}
+
+// enter 进入嵌套选项
+func (t *optionTracer) enter(option, caseName string) {
+	if t == nil {
+		return
+	}
+	t.parents = append(t.parents, option+"/"+caseName)
+}
+
+// leave 离开嵌套选项
+func (t *optionTracer) leave() {
+	if t == nil {
+		return
+	}
+	t.parents = t.parents[:len(t.parents)-1]
+}
+
+// merge 把 src 合并到 dst 中，记录覆盖的键；from 为 src 所在的局部结果（为空时 src 全部由步骤 idx 写入）
+// 写入任务合并结果的键记入对应步骤的 Keys
+func (t *optionTracer) merge(dst *traceScope, dstMap, src map[string]interface{}, prefix string, from *traceScope, idx int) {
+	root := dst == t.scopes[0]
+	for key, srcVal := range src {
+		path := prefix + key
+		writer := idx
+		if from != nil {
+			writer = from.owner(path)
+		}
+		if dstVal, exists := dstMap[key]; exists {
+			if dstSub, ok := dstVal.(map[string]interface{}); ok {
+				if srcSub, ok := srcVal.(map[string]interface{}); ok {
+					t.merge(dst, dstSub, srcSub, path+".", from, idx)
+					continue
+				}
+			}
+			if writer >= 0 {
+				t.steps[writer].Overwritten = append(t.steps[writer].Overwritten, client.OverwrittenKey{
+					Key:          path,
+					Previous:     dstVal,
+					Value:        srcVal,
+					PreviousStep: dst.owner(path),
+				})
+			}
+			dst.dropOwners(path)
+		}
+		dstMap[key] = cloneValue(srcVal)
+
+		// 从局部结果合并时保留其中各子键的写入步骤
+		written := map[string]int{path: writer}
+		if from != nil {
+			if sub := from.ownersUnder(path); len(sub) > 0 {
+				written = sub
+			}
+		}
+		for p, w := range written {
+			dst.owners[p] = w
+			if root && w >= 0 {
+				t.steps[w].Keys = append(t.steps[w].Keys, p)
+			}
+		}
+	}
+}
+
+// owner 获取写入键路径的步骤：依次查找该键、写入整个上级对象的步骤、
+// 以及（整体被覆盖的子对象）其中任一子键的写入步骤
+func (s *traceScope) owner(path string) int {
+	for p := path; ; {
+		if idx, ok := s.owners[p]; ok {
+			return idx
+		}
+		i := strings.LastIndex(p, ".")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	for p, idx := range s.owners {
+		if strings.HasPrefix(p, path+".") {
+			return idx
+		}
+	}
+	return -1
+}
+
+// ownersUnder 键路径及其子键的写入记录
+func (s *traceScope) ownersUnder(path string) map[string]int {
+	result := make(map[string]int)
+	for p, idx := range s.owners {
+		if p == path || strings.HasPrefix(p, path+".") {
+			result[p] = idx
+		}
+	}
+	return result
+}
+
+// dropOwners 移除键路径下所有子键的写入记录
+func (s *traceScope) dropOwners(path string) {
+	for p := range s.owners {
+		if p == path || strings.HasPrefix(p, path+".") {
+			delete(s.owners, p)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// interfaceFile 主配置文件名
+const interfaceFile = "interface.json"
+
+// 诊断级别
+const (
+	SeverityError   = "error"   // 会导致任务无法执行或行为错误
+	SeverityWarning = "warning" // 不影响执行，但显示或配置可能不符合预期
+)
+
+// 诊断代码
+const (
+	DiagImportFailed        = "import_failed"       // 导入文件加载失败
+	DiagI18nLoadFailed      = "i18n_load_failed"    // 国际化文件加载失败
+	DiagDuplicateTask       = "duplicate_task"      // 任务重名
+	DiagDuplicateOption     = "duplicate_option"    // 选项在多个文件中重复定义
+	DiagMissingOption       = "missing_option"      // 任务引用的选项不存在
+	DiagMissingCaseOption   = "missing_case_option" // case 引用的嵌套选项不存在
+	DiagMissingDefaultCase  = "missing_default_case"
+	DiagUnknownOptionType   = "unknown_option_type"
+	DiagUnknownController   = "unknown_controller" // 任务限定的控制器不存在
+	DiagUnknownResource     = "unknown_resource"   // 任务限定的资源不存在
+	DiagMissingTranslation  = "missing_translation"
+	DiagInvalidVerify       = "invalid_verify" // 输入的 verify 正则无效
+	DiagUnknownPipelineType = "unknown_pipeline_type"
+	DiagInvalidInputDefault = "invalid_input_default" // 输入默认值未通过校验或类型转换
+	DiagOptionCycle         = "option_cycle"          // case 嵌套选项循环引用
+	DiagOptionTooDeep       = "option_too_deep"       // 嵌套层数超过 MaxOptionDepth
+)
+
+// Diagnostic interface.json 校验诊断
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	File     string `json:"file"` // 相对 MaaEnd 目录的文件路径
+	Path     string `json:"path"` // 文件内的 JSON 路径，如 $.task[3].option[0]
+	Message  string `json:"message"`
+}
+
+// String 格式化为 文件:路径: 级别 [代码] 说明
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%s: %s [%s] %s", d.File, d.Path, d.Severity, d.Code, d.Message)
+}
+
+// HasErrors 检查诊断中是否有错误级别的条目
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 校验 interface.json 及其导入文件中的引用关系和国际化文本
+// 返回按文件和路径排序的诊断，包含加载阶段记录的问题
+func (pi *ProjectInterface) Validate() []Diagnostic {
+	v := &validator{pi: pi}
+	v.diags = append(v.diags, pi.loadDiags...)
+
+	v.checkTasks()
+	v.checkOptions()
+	v.checkOptionGraph()
+	for i, ctrl := range pi.Controllers {
+		v.checkI18n(interfaceFile, fmt.Sprintf("$.controller[%d].label", i), ctrl.Label)
+	}
+
+	sort.SliceStable(v.diags, func(i, j int) bool {
+		if v.diags[i].File != v.diags[j].File {
+			return v.diags[i].File < v.diags[j].File
+		}
+		return v.diags[i].Path < v.diags[j].Path
+	})
+	return v.diags
+}
+
+// validator 校验过程状态
+type validator struct {
+	pi    *ProjectInterface
+	diags []Diagnostic
+}
+
+func (v *validator) add(severity, code, file, path, format string, args ...interface{}) {
+	v.diags = append(v.diags, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		File:     file,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// taskSource 获取任务的定义位置
+func (v *validator) taskSource(i int) (string, string) {
+	if i < len(v.pi.taskSources) {
+		src := v.pi.taskSources[i]
+		return src.file, fmt.Sprintf("$.task[%d]", src.index)
+	}
+	return interfaceFile, fmt.Sprintf("$.task[%d]", i)
+}
+
+// optionSource 获取选项的定义位置
+func (v *validator) optionSource(name string) (string, string) {
+	file := interfaceFile
+	if src, ok := v.pi.optionSources[name]; ok {
+		file = src.file
+	}
+	return file, "$.option." + name
+}
+
+func (v *validator) checkTasks() {
+	pi := v.pi
+	firstDefined := make(map[string]int)
+
+	for i, task := range pi.Tasks {
+		file, path := v.taskSource(i)
+
+		if prev, ok := firstDefined[task.Name]; ok {
+			prevFile, prevPath := v.taskSource(prev)
+			v.add(SeverityError, DiagDuplicateTask, file, path+".name",
+				"任务 %s 与 %s:%s 重名，只有第一个定义生效", task.Name, prevFile, prevPath)
+		} else {
+			firstDefined[task.Name] = i
+		}
+
+		for j, optName := range task.Option {
+			if pi.GetOption(optName) == nil {
+				v.add(SeverityError, DiagMissingOption, file, fmt.Sprintf("%s.option[%d]", path, j),
+					"任务 %s 引用的选项 %s 不存在", task.Name, optName)
+			}
+		}
+		for j, ctrl := range task.Controller {
+			if pi.GetController(ctrl) == nil {
+				v.add(SeverityWarning, DiagUnknownController, file, fmt.Sprintf("%s.controller[%d]", path, j),
+					"任务 %s 限定的控制器 %s 不存在", task.Name, ctrl)
+			}
+		}
+		for j, res := range task.Resource {
+			if pi.GetResource(res) == nil {
+				v.add(SeverityWarning, DiagUnknownResource, file, fmt.Sprintf("%s.resource[%d]", path, j),
+					"任务 %s 限定的资源 %s 不存在", task.Name, res)
+			}
+		}
+
+		v.checkI18n(file, path+".label", task.Label)
+		v.checkI18n(file, path+".description", task.Description)
+	}
+}
+
+func (v *validator) checkOptions() {
+	names := make([]string, 0, len(v.pi.Options))
+	for name := range v.pi.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		opt := v.pi.Options[name]
+		if opt == nil {
+			continue
+		}
+		file, path := v.optionSource(name)
+
+		switch opt.Type {
+		case "select", "switch", "checkbox", "input":
+		default:
+			v.add(SeverityError, DiagUnknownOptionType, file, path+".type", "选项 %s 的类型 %q 不受支持", name, opt.Type)
+		}
+
+		// default_case 与旧字段 default 均指向 case 名称（checkbox 可用逗号分隔多个）
+		if len(opt.Cases) > 0 {
+			v.checkDefaultCase(name, opt, file, path+".default_case", opt.DefaultCase)
+			if opt.DefaultCase == "" {
+				v.checkDefaultCase(name, opt, file, path+".default", opt.Default)
+			}
+		}
+
+		for i, c := range opt.Cases {
+			casePath := fmt.Sprintf("%s.cases[%d]", path, i)
+			for j, nested := range c.Option {
+				if v.pi.GetOption(nested) == nil {
+					v.add(SeverityError, DiagMissingCaseOption, file, fmt.Sprintf("%s.option[%d]", casePath, j),
+						"选项 %s 的 case %s 引用的选项 %s 不存在", name, c.Name, nested)
+				}
+			}
+			v.checkI18n(file, casePath+".label", c.Label)
+		}
+
+		for i, input := range opt.Inputs {
+			inputPath := fmt.Sprintf("%s.inputs[%d]", path, i)
+			v.checkInput(name, &input, file, inputPath)
+			v.checkI18n(file, inputPath+".label", input.Label)
+			v.checkI18n(file, inputPath+".description", input.Description)
+		}
+
+		v.checkI18n(file, path+".label", opt.Label)
+		v.checkI18n(file, path+".description", opt.Description)
+	}
+}
+
+// checkOptionGraph 检查选项依赖图中的循环引用和任务选项的嵌套深度
+func (v *validator) checkOptionGraph() {
+	graph := v.pi.OptionGraph()
+	for _, cycle := range graph.Cycles() {
+		file, path := v.optionSource(cycle[0])
+		v.add(SeverityError, DiagOptionCycle, file, path+".cases",
+			"选项循环引用: %s", formatOptionPath(cycle))
+	}
+
+	for i, task := range v.pi.Tasks {
+		file, path := v.taskSource(i)
+		for j, optName := range task.Option {
+			if depth := graph.Depth(optName); depth > MaxOptionDepth {
+				v.add(SeverityError, DiagOptionTooDeep, file, fmt.Sprintf("%s.option[%d]", path, j),
+					"任务 %s 的选项 %s 嵌套 %d 层，超过最大 %d 层", task.Name, optName, depth, MaxOptionDepth)
+			}
+		}
+	}
+}
+
+// checkInput 检查输入的 verify 正则、pipeline_type 以及默认值
+func (v *validator) checkInput(option string, input *InputConfig, file, path string) {
+	var verify *regexp.Regexp
+	if input.Verify != "" {
+		re, err := regexp.Compile(input.Verify)
+		if err != nil {
+			v.add(SeverityError, DiagInvalidVerify, file, path+".verify",
+				"选项 %s 的输入 %s 的 verify 正则无效: %v", option, input.Name, err)
+		}
+		verify = re
+	}
+
+	if !isPipelineType(input.PipelineType) {
+		v.add(SeverityError, DiagUnknownPipelineType, file, path+".pipeline_type",
+			"选项 %s 的输入 %s 的 pipeline_type %q 不受支持（可选: int, double, bool, string）", option, input.Name, input.PipelineType)
+		return
+	}
+
+	def := input.GetDefaultString()
+	if def == "" {
+		return
+	}
+	if verify != nil && !verify.MatchString(def) {
+		v.add(SeverityWarning, DiagInvalidInputDefault, file, path+".default",
+			"选项 %s 的输入 %s 的默认值 %q 不符合校验规则 %s", option, input.Name, def, input.Verify)
+	}
+	if _, err := convertInputValue(def, input.PipelineType); err != nil {
+		v.add(SeverityWarning, DiagInvalidInputDefault, file, path+".default",
+			"选项 %s 的输入 %s 的默认值 %q %v", option, input.Name, def, err)
+	}
+}
+
+// checkDefaultCase 检查默认 case 是否存在
+func (v *validator) checkDefaultCase(name string, opt *OptionConfig, file, path, value string) {
+	if value == "" {
+		return
+	}
+	for _, caseName := range strings.Split(value, ",") {
+		caseName = strings.TrimSpace(caseName)
+		found := false
+		for _, c := range opt.Cases {
+			if c.Name == caseName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.add(SeverityError, DiagMissingDefaultCase, file, path,
+				"选项 %s 的默认 case %s 不存在", name, caseName)
+		}
+	}
+}
+
+// checkI18n 检查国际化 key 在每种已加载的语言中都有翻译
+func (v *validator) checkI18n(file, path, key string) {
+	if !strings.HasPrefix(key, "$") || len(v.pi.i18nTexts) == 0 {
+		return
+	}
+	realKey := key[1:]
+
+	var missing []string
+	for lang, texts := range v.pi.i18nTexts {
+		if _, ok := texts[realKey]; !ok {
+			missing = append(missing, lang)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	sort.Strings(missing)
+	v.add(SeverityWarning, DiagMissingTranslation, file, path,
+		"国际化文本 %s 缺少翻译: %s", key, strings.Join(missing, ", "))
+}
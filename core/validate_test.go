@@ -0,0 +1,102 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "interface.json", `{
+		"controller": [{"name": "Win32", "label": "$ctrl.win32", "type": "Win32"}],
+		"resource": [{"name": "Official", "path": ["./resource"]}],
+		"languages": {"zh_cn": "locales/zh_cn.json", "en_us": "locales/en_us.json"},
+		"import": ["tasks/daily.json", "tasks/missing.json"],
+		"task": [{"name": "Daily", "label": "$task.daily", "entry": "Daily", "option": ["Mode", "Ghost"]}],
+		"option": {
+			"Mode": {"type": "select", "label": "Mode", "default_case": "C",
+				"cases": [{"name": "A", "label": "A", "option": ["Nested"]}, {"name": "B", "label": "B"}]}
+		}
+	}`)
+	writeFile(t, dir, "tasks/daily.json", `{
+		"task": [{"name": "Daily", "label": "Daily", "entry": "Daily2", "controller": ["ADB"]}],
+		"option": {"Mode": {"type": "switch", "label": "Mode", "cases": [{"name": "Yes", "label": "Yes"}]}}
+	}`)
+	writeFile(t, dir, "locales/zh_cn.json", `{"ctrl.win32": "窗口", "task.daily": "日常"}`)
+	writeFile(t, dir, "locales/en_us.json", `{"ctrl.win32": "Window"}`)
+
+	pi, err := LoadInterface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]Diagnostic{
+		DiagImportFailed:       {Severity: SeverityError, File: "interface.json", Path: "$.import[1]"},
+		DiagDuplicateOption:    {Severity: SeverityWarning, File: "tasks/daily.json", Path: "$.option.Mode"},
+		DiagDuplicateTask:      {Severity: SeverityError, File: "tasks/daily.json", Path: "$.task[0].name"},
+		DiagMissingOption:      {Severity: SeverityError, File: "interface.json", Path: "$.task[0].option[1]"},
+		DiagUnknownController:  {Severity: SeverityWarning, File: "tasks/daily.json", Path: "$.task[0].controller[0]"},
+		DiagMissingTranslation: {Severity: SeverityWarning, File: "interface.json", Path: "$.task[0].label"},
+	}
+
+	got := make(map[string]Diagnostic)
+	for _, d := range pi.Validate() {
+		got[d.Code] = d
+	}
+	for code, w := range want {
+		d, ok := got[code]
+		if !ok {
+			t.Errorf("缺少诊断 %s", code)
+			continue
+		}
+		if d.Severity != w.Severity || d.File != w.File || d.Path != w.Path {
+			t.Errorf("%s: got %s", code, d)
+		}
+	}
+
+	// 导入文件覆盖了 Mode，原定义中的缺失 case 选项和默认 case 不再检查
+	for _, code := range []string{DiagMissingCaseOption, DiagMissingDefaultCase} {
+		if d, ok := got[code]; ok {
+			t.Errorf("不应出现诊断: %s", d)
+		}
+	}
+}
+
+func TestValidateOptionReferences(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "interface.json", `{
+		"task": [{"name": "Daily", "entry": "Daily", "option": ["Mode"]}],
+		"option": {
+			"Mode": {"type": "select", "default_case": "C",
+				"cases": [{"name": "A", "option": ["Nested"]}, {"name": "B"}]}
+		}
+	}`)
+
+	pi, err := LoadInterface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diags := pi.Validate()
+	if !HasErrors(diags) || len(diags) != 2 {
+		t.Fatalf("Validate() = %v", diags)
+	}
+	if diags[0].Code != DiagMissingCaseOption || diags[0].Path != "$.option.Mode.cases[0].option[0]" {
+		t.Errorf("got %s", diags[0])
+	}
+	if diags[1].Code != DiagMissingDefaultCase || diags[1].Path != "$.option.Mode.default_case" {
+		t.Errorf("got %s", diags[1])
+	}
+}
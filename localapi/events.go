@@ -0,0 +1,119 @@
+package localapi
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"maaend-client/client"
+)
+
+// 事件流参数
+const (
+	subscriberBuffer = 256
+	eventWriteWait   = 10 * time.Second
+	eventPingPeriod  = 30 * time.Second
+)
+
+// eventHub 任务事件广播
+type eventHub struct {
+	subscribers map[chan []byte]struct{}
+	mu          sync.Mutex
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: make(map[chan []byte]struct{})}
+}
+
+// publish 广播任务事件；订阅者处理过慢时丢弃该事件
+func (h *eventHub) publish(msg *client.Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+// subscribe 添加订阅者
+func (h *eventHub) subscribe() chan []byte {
+	ch := make(chan []byte, subscriberBuffer)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+// unsubscribe 移除订阅者
+func (h *eventHub) unsubscribe(ch chan []byte) {
+	h.mu.Lock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+	h.mu.Unlock()
+}
+
+// closeAll 关闭所有订阅者
+func (h *eventHub) closeAll() {
+	h.mu.Lock()
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+	h.mu.Unlock()
+}
+
+// serve 向 WebSocket 连接推送事件，直到连接断开
+func (h *eventHub) serve(conn *websocket.Conn) {
+	ch := h.subscribe()
+	defer h.unsubscribe(ch)
+	defer conn.Close()
+
+	// 读取协程：处理控制帧并检测断开
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(eventPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case data, ok := <-ch:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(eventWriteWait))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(eventWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				log.Printf("[LocalAPI] 推送事件失败: %v", err)
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(eventWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package localapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"maaend-client/client"
+	"maaend-client/config"
+)
+
+// 接口路径前缀
+const apiPrefix = "/api/v1"
+
+// maxBodySize 请求体大小上限
+const maxBodySize = 1 << 20
+
+// Server 本地控制 API
+//
+//	GET  /api/v1/capabilities     设备能力（CapabilitiesPayload，?lang= 指定语言，?all=true 附带所有语言）
+//	GET  /api/v1/status           连接与任务状态
+//	POST /api/v1/jobs             启动任务（RunTaskPayload，job_id 可省略）
+//	POST /api/v1/jobs/{id}/stop   停止任务
+//	GET  /api/v1/screenshot       截图（ScreenshotPayload）
+//	GET  /api/v1/events           WebSocket 事件流（task_status、task_log、task_completed 消息）
+type Server struct {
+	cfg     config.LocalAPIConfig
+	client  *client.Client
+	wrapper client.MaaWrapperInterface
+	hub     *eventHub
+
+	httpServer *http.Server
+	upgrader   websocket.Upgrader
+}
+
+// StatusResponse 状态响应
+type StatusResponse struct {
+	Connected  bool                    `json:"connected"`
+	DeviceID   string                  `json:"device_id,omitempty"`
+	ActiveURL  string                  `json:"active_url,omitempty"`
+	Version    string                  `json:"version"`
+	CurrentJob *JobInfo                `json:"current_job,omitempty"`
+	Endpoints  []client.EndpointHealth `json:"endpoints"`
+}
+
+// JobInfo 任务信息
+type JobInfo struct {
+	JobID      string    `json:"job_id"`
+	Controller string    `json:"controller"`
+	Resource   string    `json:"resource"`
+	Tasks      []string  `json:"tasks"`
+	Origin     string    `json:"origin"`
+	Status     string    `json:"status"`
+	StartTime  time.Time `json:"start_time"`
+}
+
+// errorResponse 错误响应
+type errorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// NewServer 创建本地 API 服务，并订阅客户端的任务事件
+func NewServer(cfg config.LocalAPIConfig, c *client.Client, wrapper client.MaaWrapperInterface) (*Server, error) {
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("未配置 local_api.token")
+	}
+
+	s := &Server{
+		cfg:     cfg,
+		client:  c,
+		wrapper: wrapper,
+		hub:     newEventHub(),
+		upgrader: websocket.Upgrader{
+			// 来源已由 authenticate 检查（无 Origin 或本机页面）
+			CheckOrigin: originAllowed,
+		},
+	}
+	c.SetJobEventHandler(s.hub.publish)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+apiPrefix+"/capabilities", s.handleCapabilities)
+	mux.HandleFunc("GET "+apiPrefix+"/status", s.handleStatus)
+	mux.HandleFunc("POST "+apiPrefix+"/jobs", s.handleRunJob)
+	mux.HandleFunc("POST "+apiPrefix+"/jobs/{id}/stop", s.handleStopJob)
+	mux.HandleFunc("GET "+apiPrefix+"/screenshot", s.handleScreenshot)
+	mux.HandleFunc("GET "+apiPrefix+"/events", s.handleEvents)
+
+	s.httpServer = &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           s.authenticate(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s, nil
+}
+
+// Run 启动服务（阻塞），ctx 取消时关闭
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %w", s.cfg.Listen, err)
+	}
+	log.Printf("[LocalAPI] 本地控制 API 已启动: http://%s%s", ln.Addr(), apiPrefix)
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.hub.closeAll()
+		s.httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := s.httpServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authenticate 检查请求来源并校验访问令牌
+//
+// 防止网页通过跨站请求或 DNS 重绑定访问本机接口：监听本机地址时 Host 必须是本机名称，
+// 浏览器请求的 Origin 必须是本机页面；请求体限制为 maxBodySize
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	loopback := isLoopback(s.cfg.Listen)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if loopback && !isLoopbackHost(r.Host) {
+			writeError(w, http.StatusForbidden, "host_not_allowed", "仅允许通过本机地址访问")
+			return
+		}
+		if !originAllowed(r) {
+			writeError(w, http.StatusForbidden, "origin_not_allowed", "不允许来自其他网页的请求")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" && websocket.IsWebSocketUpgrade(r) {
+			// 浏览器 WebSocket 无法设置请求头，允许通过查询参数传递
+			token = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "访问令牌无效")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleCapabilities 获取设备能力（?lang= 指定语言，?all=true 附带所有语言）
+func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	all, _ := strconv.ParseBool(query.Get("all"))
+	caps, err := s.wrapper.GetCapabilities(query.Get("lang"), all)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "capabilities_failed", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, caps)
+}
+
+// handleStatus 获取连接与任务状态
+func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	resp := &StatusResponse{
+		Connected: s.client.IsConnected(),
+		DeviceID:  s.client.GetDeviceID(),
+		ActiveURL: s.client.GetActiveURL(),
+		Version:   s.wrapper.GetVersion(),
+		Endpoints: s.client.GetEndpointHealth(),
+	}
+	if job := s.client.GetCurrentJob(); job != nil {
+		resp.CurrentJob = jobInfo(job)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleRunJob 启动任务
+func (s *Server) handleRunJob(w http.ResponseWriter, r *http.Request) {
+	// 只接受 JSON，浏览器无法在不经预检的跨站请求中发送 application/json
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type 必须为 application/json")
+		return
+	}
+
+	var payload client.RunTaskPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "payload_too_large", err.Error())
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid_payload", err.Error())
+		return
+	}
+	if len(payload.Tasks) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_payload", "tasks 不能为空")
+		return
+	}
+	if payload.JobID == "" {
+		payload.JobID = fmt.Sprintf("local-%d", time.Now().UnixNano())
+	}
+
+	job := &client.Job{
+		JobID:      payload.JobID,
+		Controller: payload.Controller,
+		Resource:   payload.Resource,
+		Tasks:      payload.Tasks,
+		Origin:     client.JobOriginLocal,
+
+		StrictOptions: payload.StrictOptions,
+	}
+	if err := s.client.StartJob(job); err != nil {
+		switch {
+		case errors.Is(err, client.ErrDeviceBusy):
+			writeError(w, http.StatusConflict, "device_busy", err.Error())
+		case errors.Is(err, client.ErrMaaNotReady):
+			writeError(w, http.StatusServiceUnavailable, "maa_not_ready", err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, "start_failed", err.Error())
+		}
+		return
+	}
+
+	log.Printf("[LocalAPI] 启动任务: %s", job.JobID)
+	writeJSON(w, http.StatusAccepted, jobInfo(job))
+}
+
+// handleStopJob 停止任务
+func (s *Server) handleStopJob(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if err := s.client.StopJob(jobID); err != nil {
+		if errors.Is(err, client.ErrJobNotFound) {
+			writeError(w, http.StatusNotFound, "job_not_found", err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "stop_failed", err.Error())
+		return
+	}
+
+	log.Printf("[LocalAPI] 停止任务: %s", jobID)
+	writeJSON(w, http.StatusAccepted, &client.StopTaskPayload{JobID: jobID})
+}
+
+// handleScreenshot 截图
+func (s *Server) handleScreenshot(w http.ResponseWriter, _ *http.Request) {
+	imageData, width, height, err := s.wrapper.TakeScreenshot()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "screenshot_failed", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, &client.ScreenshotPayload{
+		Base64Image: base64.StdEncoding.EncodeToString(imageData),
+		Width:       width,
+		Height:      height,
+	})
+}
+
+// handleEvents WebSocket 事件流
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.hub.serve(conn)
+}
+
+// jobInfo 转换任务信息
+func jobInfo(job *client.Job) *JobInfo {
+	tasks := make([]string, 0, len(job.Tasks))
+	for _, t := range job.Tasks {
+		tasks = append(tasks, t.Name)
+	}
+	return &JobInfo{
+		JobID:      job.JobID,
+		Controller: job.Controller,
+		Resource:   job.Resource,
+		Tasks:      tasks,
+		Origin:     job.Origin,
+		Status:     job.Status,
+		StartTime:  job.StartTime,
+	}
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError 输出错误响应
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, &errorResponse{Error: code, Message: message})
+}
+
+// isLoopback 检查监听地址是否仅限本机
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	return isLoopbackName(host)
+}
+
+// isLoopbackHost 检查请求的 Host（可带端口）是否为本机名称
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return isLoopbackName(strings.Trim(host, "[]"))
+}
+
+// isLoopbackName 检查主机名是否为 localhost 或回环地址
+func isLoopbackName(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// originAllowed 检查请求来源：没有 Origin（非浏览器客户端）或来自本机页面
+func originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return isLoopbackName(u.Hostname())
+}
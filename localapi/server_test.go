@@ -0,0 +1,202 @@
+package localapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"maaend-client/client"
+	"maaend-client/config"
+)
+
+// fakeWrapper 阻塞执行任务直到被停止
+type fakeWrapper struct {
+	stop chan struct{}
+}
+
+func (f *fakeWrapper) GetCapabilities(string, bool) (*client.CapabilitiesPayload, error) {
+	return &client.CapabilitiesPayload{Controllers: []string{"Win32"}}, nil
+}
+
+func (f *fakeWrapper) RunTask(job *client.Job, statusCh chan<- client.TaskStatusPayload, _ chan<- client.TaskLogPayload) error {
+	statusCh <- client.TaskStatusPayload{JobID: job.JobID, Status: "running", CurrentTask: job.Tasks[0].Name}
+	<-f.stop
+	return nil
+}
+
+func (f *fakeWrapper) StopTask() error {
+	close(f.stop)
+	return nil
+}
+
+func (f *fakeWrapper) TakeScreenshot() ([]byte, int, int, error) { return []byte("png"), 1, 1, nil }
+func (f *fakeWrapper) ClearEventChannels()                       {}
+func (f *fakeWrapper) GetVersion() string                        { return "test" }
+func (f *fakeWrapper) ExplainOptions(string, map[string]interface{}) (*client.OptionExplanation, error) {
+	return &client.OptionExplanation{}, nil
+}
+func (f *fakeWrapper) ReloadInterface() (*client.InterfaceReloadResult, error) {
+	return &client.InterfaceReloadResult{}, nil
+}
+func (f *fakeWrapper) ReloadResource(string) (*client.ResourceReloadResult, error) {
+	return &client.ResourceReloadResult{}, nil
+}
+
+func newTestServer(t *testing.T, token string) (*httptest.Server, *fakeWrapper) {
+	t.Helper()
+	wrapper := &fakeWrapper{stop: make(chan struct{})}
+	c := client.NewClient(&config.Config{})
+	c.SetMaaWrapper(wrapper)
+
+	s, err := NewServer(config.LocalAPIConfig{Listen: "127.0.0.1:0", Token: token}, c, wrapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s.httpServer.Handler)
+	t.Cleanup(ts.Close)
+	return ts, wrapper
+}
+
+func request(t *testing.T, method, url, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAuthentication(t *testing.T) {
+	ts, _ := newTestServer(t, "secret")
+
+	if resp := request(t, "GET", ts.URL+apiPrefix+"/status", "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("未带令牌: status = %d", resp.StatusCode)
+	}
+	if resp := request(t, "GET", ts.URL+apiPrefix+"/status", "secret", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("带令牌: status = %d", resp.StatusCode)
+	}
+
+	if _, err := NewServer(config.LocalAPIConfig{Listen: "127.0.0.1:15619"}, client.NewClient(&config.Config{}), &fakeWrapper{}); err == nil {
+		t.Fatal("未配置令牌时应拒绝启动")
+	}
+}
+
+func TestRequestChecks(t *testing.T) {
+	ts, _ := newTestServer(t, "secret")
+	body := `{"job_id":"j1","controller":"Win32","resource":"Official","tasks":[{"name":"Daily"}]}`
+
+	send := func(header map[string]string, host, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", ts.URL+apiPrefix+"/jobs", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		if host != "" {
+			req.Host = host
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	cases := []struct {
+		name   string
+		header map[string]string
+		host   string
+		body   string
+		want   int
+	}{
+		{"text/plain", map[string]string{"Content-Type": "text/plain"}, "", body, http.StatusUnsupportedMediaType},
+		{"DNS 重绑定", map[string]string{"Content-Type": "application/json"}, "evil.example:15619", body, http.StatusForbidden},
+		{"跨站来源", map[string]string{"Content-Type": "application/json", "Origin": "https://evil.example"}, "", body, http.StatusForbidden},
+		{"请求体过大", map[string]string{"Content-Type": "application/json"}, "", `{"job_id":"` + strings.Repeat("a", maxBodySize) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		if got := send(tc.header, tc.host, tc.body); got != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+
+	// 查询参数中的令牌仅用于 WebSocket
+	if resp := request(t, "GET", ts.URL+apiPrefix+"/status?token=secret", "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("查询参数令牌: status = %d", resp.StatusCode)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + apiPrefix + "/events?token=secret"
+	header := http.Header{"Origin": {"https://evil.example"}}
+	if conn, _, err := websocket.DefaultDialer.Dial(wsURL, header); err == nil {
+		conn.Close()
+		t.Error("跨站来源的 WebSocket 连接应被拒绝")
+	}
+	header.Set("Origin", "http://localhost:3000")
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		t.Fatalf("本机页面的 WebSocket 连接失败: %v", err)
+	}
+	conn.Close()
+}
+
+func TestJobLifecycle(t *testing.T) {
+	ts, _ := newTestServer(t, "secret")
+
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + apiPrefix + "/events"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": {"Bearer secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	body := `{"job_id":"j1","controller":"Win32","resource":"Official","tasks":[{"name":"Daily"}]}`
+	if resp := request(t, "POST", ts.URL+apiPrefix+"/jobs", "secret", body); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("启动任务: status = %d", resp.StatusCode)
+	}
+	if resp := request(t, "POST", ts.URL+apiPrefix+"/jobs", "secret", body); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("重复启动: status = %d", resp.StatusCode)
+	}
+	if resp := request(t, "POST", ts.URL+apiPrefix+"/jobs/other/stop", "secret", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("停止不存在的任务: status = %d", resp.StatusCode)
+	}
+	if resp := request(t, "POST", ts.URL+apiPrefix+"/jobs/j1/stop", "secret", ""); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("停止任务: status = %d", resp.StatusCode)
+	}
+
+	// 事件流应依次收到 task_status 并以 task_completed 结束
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("读取事件失败: %v", err)
+		}
+		var msg client.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type == client.MsgTypeTaskCompleted {
+			if !bytes.Contains(msg.Payload, []byte(`"completed"`)) {
+				t.Fatalf("任务结果错误: %s", msg.Payload)
+			}
+			break
+		}
+	}
+}
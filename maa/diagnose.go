@@ -0,0 +1,61 @@
+package maa
+
+import (
+	"fmt"
+
+	maafw "github.com/MaaXYZ/maa-framework-go/v3"
+)
+
+// WindowInfo 桌面窗口信息
+type WindowInfo struct {
+	ClassName  string `json:"class_name"`
+	WindowName string `json:"window_name"`
+	Matched    bool   `json:"matched"` // 是否匹配控制器的窗口规则
+}
+
+// AdbDeviceInfo ADB 设备信息
+type AdbDeviceInfo struct {
+	Name    string `json:"name"`
+	AdbPath string `json:"adb_path"`
+	Address string `json:"address"`
+}
+
+// FindWindows 列出桌面窗口，并标记与 Win32 控制器窗口规则匹配的窗口
+func (w *Wrapper) FindWindows(controller string) ([]WindowInfo, error) {
+	if !w.initialized {
+		return nil, fmt.Errorf("MaaFramework 未初始化")
+	}
+	ctrl := w.project().GetController(controller)
+	if ctrl == nil || ctrl.Win32 == nil {
+		return nil, fmt.Errorf("控制器 %s 不是 Win32 控制器", controller)
+	}
+
+	windows := maafw.FindDesktopWindows()
+	infos := make([]WindowInfo, 0, len(windows))
+	for _, win := range windows {
+		infos = append(infos, WindowInfo{
+			ClassName:  win.ClassName,
+			WindowName: win.WindowName,
+			Matched:    matchWindow(win, ctrl.Win32.ClassRegex, ctrl.Win32.WindowRegex),
+		})
+	}
+	return infos, nil
+}
+
+// FindAdbDevices 列出可用的 ADB 设备
+func (w *Wrapper) FindAdbDevices() ([]AdbDeviceInfo, error) {
+	if !w.initialized {
+		return nil, fmt.Errorf("MaaFramework 未初始化")
+	}
+
+	devices := maafw.FindAdbDevices()
+	infos := make([]AdbDeviceInfo, 0, len(devices))
+	for _, device := range devices {
+		infos = append(infos, AdbDeviceInfo{
+			Name:    device.Name,
+			AdbPath: device.AdbPath,
+			Address: device.Address,
+		})
+	}
+	return infos, nil
+}
@@ -0,0 +1,148 @@
+package maa
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"maaend-client/client"
+	"maaend-client/config"
+	"maaend-client/core"
+)
+
+// 项目接口热重载：新的 ProjectInterface 完整加载后一次性替换，任务执行期间只标记待重载，
+// 在任务结束后应用，保证同一个任务始终使用同一份 interface.json
+
+// loadProject 加载并校验 interface.json，应用本地配置覆盖
+func (w *Wrapper) loadProject() (*core.ProjectInterface, error) {
+	pi, err := core.LoadInterface(w.maaEndPath)
+	if err != nil {
+		return nil, fmt.Errorf("加载 interface.json 失败: %w", err)
+	}
+	logDiagnostics(pi.Validate())
+	if cfg := config.Get(); cfg != nil {
+		applyWin32Overrides(pi, cfg.MaaEnd.Win32ClassRegex, cfg.MaaEnd.Win32WindowRegex)
+		pi.SetLanguageFallback(cfg.MaaEnd.LanguageFallback)
+	}
+	return pi, nil
+}
+
+// project 获取当前的项目接口
+func (w *Wrapper) project() *core.ProjectInterface {
+	w.piMu.RLock()
+	defer w.piMu.RUnlock()
+	return w.pi
+}
+
+// SetReloadHandler 设置项目接口重新加载后的回调（重新上报设备能力）
+func (w *Wrapper) SetReloadHandler(fn func()) {
+	w.mu.Lock()
+	w.onReload = fn
+	w.mu.Unlock()
+}
+
+// WatchInterface 监视 interface.json、import 文件和翻译文件，变化后自动重新加载
+func (w *Wrapper) WatchInterface() error {
+	pi := w.project()
+	if pi == nil {
+		return fmt.Errorf("MaaFramework 未初始化")
+	}
+
+	watcher, err := newFileWatcher(func(changed []string) {
+		log.Printf("[Maa] 检测到项目文件变化: %v", changed)
+		if _, err := w.ReloadInterface(); err != nil {
+			log.Printf("[Maa] %v", err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("创建文件监视器失败: %w", err)
+	}
+	watcher.SetFiles(pi.InterfaceFiles())
+
+	w.mu.Lock()
+	w.interfaceWatcher = watcher
+	w.mu.Unlock()
+	log.Printf("[Maa] 已开始监视 interface.json 及其引用的文件")
+	return nil
+}
+
+// ReloadInterface 重新加载 interface.json；任务执行中时标记待重载，任务结束后自动应用
+func (w *Wrapper) ReloadInterface() (*client.InterfaceReloadResult, error) {
+	w.mu.Lock()
+	if !w.initialized {
+		w.mu.Unlock()
+		return nil, fmt.Errorf("MaaFramework 未初始化")
+	}
+	if w.running {
+		w.reloadPending = true
+		w.mu.Unlock()
+		log.Printf("[Maa] 任务执行中，将在任务结束后重新加载 interface.json")
+		return &client.InterfaceReloadResult{Pending: true}, nil
+	}
+	w.mu.Unlock()
+
+	pi, err := w.loadProject()
+	if err != nil {
+		// 保留旧的项目接口
+		return nil, fmt.Errorf("重新加载失败，继续使用当前版本: %w", err)
+	}
+
+	w.mu.Lock()
+	if w.running {
+		// 加载期间开始了新任务，等任务结束后重新加载
+		w.reloadPending = true
+		w.mu.Unlock()
+		return &client.InterfaceReloadResult{Pending: true}, nil
+	}
+	w.reloadPending = false
+
+	old := w.project()
+	if w.currentResource != "" && !slices.Equal(old.GetResourcePaths(w.currentResource), pi.GetResourcePaths(w.currentResource)) {
+		// 资源路径变化，下次任务重新加载资源
+		w.resourceStale = true
+	}
+	w.piMu.Lock()
+	w.pi = pi
+	w.piMu.Unlock()
+
+	if w.interfaceWatcher != nil {
+		w.interfaceWatcher.SetFiles(pi.InterfaceFiles())
+	}
+	onReload := w.onReload
+	w.mu.Unlock()
+
+	log.Printf("[Maa] 已重新加载项目: %s v%s (%d 个任务)", pi.Name, pi.Version, len(pi.Tasks))
+	if onReload != nil {
+		onReload()
+	}
+	return reloadResult(pi), nil
+}
+
+// finishJob 任务结束，应用任务期间的待重载（先重新加载项目接口，资源路径可能随之变化）
+func (w *Wrapper) finishJob() {
+	w.mu.Lock()
+	w.running = false
+	pending := w.reloadPending
+	w.mu.Unlock()
+
+	if pending {
+		if _, err := w.ReloadInterface(); err != nil {
+			log.Printf("[Maa] %v", err)
+		}
+	}
+	w.reloadPendingResource()
+}
+
+// reloadResult 重新加载结果：版本、任务数和校验错误
+func reloadResult(pi *core.ProjectInterface) *client.InterfaceReloadResult {
+	result := &client.InterfaceReloadResult{
+		Version: pi.Version,
+		Tasks:   len(pi.Tasks),
+	}
+	for _, d := range pi.Validate() {
+		if d.Severity == core.SeverityError {
+			result.Errors = append(result.Errors, d.String())
+		}
+	}
+	return result
+}
@@ -1,5 +1,16 @@
 package maa
 
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	maafw "github.com/MaaXYZ/maa-framework-go/v3"
+
+	"maaend-client/client"
+)
+
 // 资源管理相关辅助函数
 
 // ResourceInfo 资源信息
@@ -10,11 +21,12 @@ type ResourceInfo struct {
 
 // GetResourceInfo 获取资源信息
 func (w *Wrapper) GetResourceInfo(name string) *ResourceInfo {
-	if w.pi == nil {
+	pi := w.project()
+	if pi == nil {
 		return nil
 	}
 
-	paths := w.pi.GetResourcePaths(name)
+	paths := pi.GetResourcePaths(name)
 	if paths == nil {
 		return nil
 	}
@@ -27,15 +39,16 @@ func (w *Wrapper) GetResourceInfo(name string) *ResourceInfo {
 
 // GetAllResources 获取所有资源
 func (w *Wrapper) GetAllResources() []ResourceInfo {
-	if w.pi == nil {
+	pi := w.project()
+	if pi == nil {
 		return nil
 	}
 
-	names := w.pi.GetResourceNames()
+	names := pi.GetResourceNames()
 	resources := make([]ResourceInfo, 0, len(names))
 
 	for _, name := range names {
-		paths := w.pi.GetResourcePaths(name)
+		paths := pi.GetResourcePaths(name)
 		resources = append(resources, ResourceInfo{
 			Name:  name,
 			Paths: paths,
@@ -44,3 +57,127 @@ func (w *Wrapper) GetAllResources() []ResourceInfo {
 
 	return resources
 }
+
+// buildResource 创建资源并加载所有资源路径，全部成功后替换当前资源（需持有 mu）
+// 返回每个路径的加载结果；有路径失败时保留原有资源并返回错误
+func (w *Wrapper) buildResource(name string) ([]client.ResourceBundleResult, error) {
+	paths := w.project().GetResourcePaths(name)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("资源不存在: %s", name)
+	}
+
+	log.Printf("[Maa] 加载资源: %s", name)
+
+	res := maafw.NewResource()
+	if res == nil {
+		return nil, fmt.Errorf("创建资源失败")
+	}
+
+	results := make([]client.ResourceBundleResult, 0, len(paths))
+	var failed []string
+	for _, path := range paths {
+		log.Printf("[Maa] 加载资源路径: %s", path)
+		result := client.ResourceBundleResult{Path: path, Success: true}
+		if !res.PostBundle(path).Wait().Success() {
+			result.Success = false
+			result.Error = bundleError(path)
+			failed = append(failed, path)
+			log.Printf("[Maa] 资源路径加载失败: %s (%s)", path, result.Error)
+		}
+		results = append(results, result)
+	}
+	if len(failed) > 0 {
+		res.Destroy()
+		return results, fmt.Errorf("资源 %s 加载失败: %s", name, strings.Join(failed, ", "))
+	}
+
+	// 释放旧资源
+	if w.resource != nil {
+		w.resource.Destroy()
+	}
+	w.resource = res
+	w.currentResource = name
+	w.resourceStale = false
+	if w.resourceWatcher != nil {
+		w.resourceWatcher.SetDirs(paths)
+	}
+
+	log.Printf("[Maa] 资源加载完成: %s", name)
+	return results, nil
+}
+
+// bundleError 资源路径加载失败的原因
+func bundleError(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "路径不存在"
+		}
+		return err.Error()
+	}
+	return "PostBundle 失败，详见 MaaFramework 日志"
+}
+
+// WatchResources 监视当前资源的所有路径，文件变化后在下次任务前重新加载资源
+func (w *Wrapper) WatchResources() error {
+	watcher, err := newFileWatcher(func(changed []string) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.resource == nil {
+			return
+		}
+		w.resourceStale = true
+		log.Printf("[Maa] 检测到 %d 个资源文件变化，将在下次任务前重新加载资源 %s", len(changed), w.currentResource)
+	})
+	if err != nil {
+		return fmt.Errorf("创建文件监视器失败: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.currentResource != "" {
+		watcher.SetDirs(w.project().GetResourcePaths(w.currentResource))
+	}
+	w.resourceWatcher = watcher
+	return nil
+}
+
+// ReloadResource 重新加载资源，name 为空时为当前资源；任务执行中时记录请求，任务结束后加载
+func (w *Wrapper) ReloadResource(name string) (*client.ResourceReloadResult, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.initialized {
+		return nil, fmt.Errorf("MaaFramework 未初始化")
+	}
+	if name == "" {
+		name = w.currentResource
+	}
+	if name == "" {
+		return nil, fmt.Errorf("尚未加载资源，请指定资源名称")
+	}
+
+	if w.running {
+		w.pendingResource = name
+		log.Printf("[Maa] 任务执行中，将在任务结束后重新加载资源 %s", name)
+		return &client.ResourceReloadResult{Resource: name, Pending: true}, nil
+	}
+
+	bundles, err := w.buildResource(name)
+	return &client.ResourceReloadResult{Resource: name, Bundles: bundles}, err
+}
+
+// reloadPendingResource 加载任务执行期间请求重新加载的资源
+func (w *Wrapper) reloadPendingResource() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	name := w.pendingResource
+	if name == "" || w.running {
+		// 已开始新任务时留到该任务结束后
+		return
+	}
+	w.pendingResource = ""
+	if _, err := w.buildResource(name); err != nil {
+		log.Printf("[Maa] %v", err)
+	}
+}
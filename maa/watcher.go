@@ -0,0 +1,211 @@
+package maa
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce 文件变化后等待的时间，期间的多次变化合并为一次回调（编辑器保存、批量更新资源）
+const watchDebounce = 500 * time.Millisecond
+
+// fileWatcher 监视一组文件和目录树，变化稳定后回调
+// 文件通过其所在目录监视，编辑器以“写临时文件再替换”方式保存时也能收到事件；
+// 目录树下的所有子目录都会被监视（fsnotify 不支持递归），新建的子目录自动加入
+type fileWatcher struct {
+	watcher  *fsnotify.Watcher
+	onChange func(changed []string)
+
+	mu      sync.Mutex
+	files   map[string]bool // 监视的文件（Clean 后的完整路径）
+	roots   map[string]bool // 监视的目录树根目录
+	dirs    map[string]bool // 已添加到 fsnotify 的目录
+	changed map[string]bool // 等待回调的变化
+	timer   *time.Timer
+
+	done chan struct{}
+}
+
+// newFileWatcher 创建文件监视器
+func newFileWatcher(onChange func(changed []string)) (*fileWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	fw := &fileWatcher{
+		watcher:  watcher,
+		onChange: onChange,
+		files:    make(map[string]bool),
+		roots:    make(map[string]bool),
+		dirs:     make(map[string]bool),
+		changed:  make(map[string]bool),
+		done:     make(chan struct{}),
+	}
+	go fw.loop()
+	return fw, nil
+}
+
+// SetFiles 替换监视的文件列表（重新加载后 import、翻译文件可能变化）
+func (fw *fileWatcher) SetFiles(files []string) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	fw.files = make(map[string]bool, len(files))
+	for _, file := range files {
+		fw.files[filepath.Clean(file)] = true
+	}
+	fw.refresh()
+}
+
+// SetDirs 替换监视的目录树列表（切换资源后资源目录变化）
+func (fw *fileWatcher) SetDirs(roots []string) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	fw.roots = make(map[string]bool, len(roots))
+	for _, root := range roots {
+		fw.roots[filepath.Clean(root)] = true
+	}
+	fw.refresh()
+}
+
+// refresh 按当前的文件和目录树更新 fsnotify 监视的目录（需持有 mu）
+func (fw *fileWatcher) refresh() {
+	dirs := make(map[string]bool)
+	for file := range fw.files {
+		dirs[filepath.Dir(file)] = true
+	}
+	for root := range fw.roots {
+		for _, dir := range walkDirs(root) {
+			dirs[dir] = true
+		}
+	}
+
+	for dir := range fw.dirs {
+		if !dirs[dir] {
+			fw.watcher.Remove(dir)
+			delete(fw.dirs, dir)
+		}
+	}
+	for dir := range dirs {
+		fw.addDir(dir)
+	}
+}
+
+// addDir 添加监视目录（需持有 mu）
+func (fw *fileWatcher) addDir(dir string) {
+	if fw.dirs[dir] {
+		return
+	}
+	if err := fw.watcher.Add(dir); err != nil {
+		log.Printf("[Maa] 无法监视目录 %s: %v", dir, err)
+		return
+	}
+	fw.dirs[dir] = true
+}
+
+// underRoot 路径是否位于某个监视的目录树中（需持有 mu）
+func (fw *fileWatcher) underRoot(name string) bool {
+	for root := range fw.roots {
+		if name == root || strings.HasPrefix(name, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkDirs 列出目录及其所有子目录，目录不存在时返回空
+func walkDirs(root string) []string {
+	var dirs []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs
+}
+
+// Close 停止监视
+func (fw *fileWatcher) Close() {
+	fw.mu.Lock()
+	if fw.timer != nil {
+		fw.timer.Stop()
+	}
+	fw.mu.Unlock()
+	fw.watcher.Close()
+	<-fw.done
+}
+
+// loop 处理 fsnotify 事件
+func (fw *fileWatcher) loop() {
+	defer close(fw.done)
+	for {
+		select {
+		case event, ok := <-fw.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			fw.record(filepath.Clean(event.Name))
+		case err, ok := <-fw.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("[Maa] 文件监视错误: %v", err)
+		}
+	}
+}
+
+// record 记录监视文件的变化，并重新开始计时
+func (fw *fileWatcher) record(name string) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	switch {
+	case fw.files[name]:
+	case fw.underRoot(name):
+		// 新建的子目录（含其中已有的子目录）也需要监视
+		if info, err := os.Stat(name); err == nil && info.IsDir() {
+			for _, dir := range walkDirs(name) {
+				fw.addDir(dir)
+			}
+		}
+	default:
+		return
+	}
+	fw.changed[name] = true
+	if fw.timer != nil {
+		fw.timer.Stop()
+	}
+	fw.timer = time.AfterFunc(watchDebounce, fw.flush)
+}
+
+// flush 回调期间累积的变化
+func (fw *fileWatcher) flush() {
+	fw.mu.Lock()
+	changed := make([]string, 0, len(fw.changed))
+	for name := range fw.changed {
+		changed = append(changed, name)
+	}
+	fw.changed = make(map[string]bool)
+	fw.mu.Unlock()
+
+	if len(changed) == 0 {
+		return
+	}
+	sort.Strings(changed)
+	fw.onChange(changed)
+}
@@ -0,0 +1,84 @@
+package maa
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher(t *testing.T) {
+	dir := t.TempDir()
+	watched := filepath.Join(dir, "interface.json")
+	other := filepath.Join(dir, "other.json")
+	for _, path := range []string{watched, other} {
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes := make(chan []string, 4)
+	fw, err := newFileWatcher(func(changed []string) { changes <- changed })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+	fw.SetFiles([]string{watched})
+
+	// 未监视的文件不触发回调
+	os.WriteFile(other, []byte(`{"a":1}`), 0644)
+	// 多次写入合并为一次回调
+	os.WriteFile(watched, []byte(`{"a":1}`), 0644)
+	os.WriteFile(watched, []byte(`{"a":2}`), 0644)
+
+	select {
+	case changed := <-changes:
+		if !reflect.DeepEqual(changed, []string{watched}) {
+			t.Errorf("changed = %v", changed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("未收到文件变化回调")
+	}
+	select {
+	case changed := <-changes:
+		t.Errorf("多余的回调: %v", changed)
+	case <-time.After(2 * watchDebounce):
+	}
+}
+
+func TestFileWatcherDirs(t *testing.T) {
+	root := t.TempDir()
+
+	changes := make(chan []string, 4)
+	fw, err := newFileWatcher(func(changed []string) { changes <- changed })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+	fw.SetDirs([]string{root})
+
+	wait := func() []string {
+		t.Helper()
+		select {
+		case changed := <-changes:
+			return changed
+		case <-time.After(5 * time.Second):
+			t.Fatal("未收到目录变化回调")
+			return nil
+		}
+	}
+
+	// 新建的子目录自动加入监视
+	sub := filepath.Join(root, "pipeline")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	wait()
+
+	file := filepath.Join(sub, "Daily.json")
+	os.WriteFile(file, []byte("{}"), 0644)
+	if changed := wait(); !reflect.DeepEqual(changed, []string{file}) {
+		t.Errorf("changed = %v", changed)
+	}
+}
@@ -21,7 +21,8 @@ import (
 // Wrapper MaaFramework 封装
 type Wrapper struct {
 	maaEndPath string
-	pi         *core.ProjectInterface
+	pi         *core.ProjectInterface // 通过 project() 读取，热重载时整体替换
+	piMu       sync.RWMutex
 
 	controller *maafw.Controller
 	resource   *maafw.Resource
@@ -43,6 +44,15 @@ type Wrapper struct {
 
 	// 任务控制
 	stopRequested bool
+	running       bool
+
+	// 热重载
+	interfaceWatcher *fileWatcher
+	reloadPending    bool   // 任务执行期间检测到变化，任务结束后重新加载
+	onReload         func() // 重新加载后的回调
+	resourceWatcher  *fileWatcher
+	resourceStale    bool   // 资源文件已变化，下次任务前重新加载
+	pendingResource  string // 任务执行期间请求重新加载的资源，任务结束后加载
 }
 
 // NewWrapper 创建 Wrapper
@@ -65,14 +75,13 @@ func (w *Wrapper) Init() error {
 	log.Printf("[Maa] 初始化 MaaFramework...")
 
 	// 加载 interface.json
-	pi, err := core.LoadInterface(w.maaEndPath)
+	pi, err := w.loadProject()
 	if err != nil {
-		return fmt.Errorf("加载 interface.json 失败: %w", err)
-	}
-	if cfg := config.Get(); cfg != nil {
-		applyWin32Overrides(pi, cfg.MaaEnd.Win32ClassRegex, cfg.MaaEnd.Win32WindowRegex)
+		return err
 	}
+	w.piMu.Lock()
 	w.pi = pi
+	w.piMu.Unlock()
 	log.Printf("[Maa] 加载项目: %s v%s", pi.Name, pi.Version)
 
 	// 初始化日志目录
@@ -96,6 +105,21 @@ func (w *Wrapper) Init() error {
 	return nil
 }
 
+// logDiagnostics 输出 interface.json 校验结果：错误逐条输出，警告仅输出数量
+func logDiagnostics(diags []core.Diagnostic) {
+	warnings := 0
+	for _, d := range diags {
+		if d.Severity == core.SeverityError {
+			log.Printf("[Maa] interface.json 错误: %s", d)
+		} else {
+			warnings++
+		}
+	}
+	if warnings > 0 {
+		log.Printf("[Maa] interface.json 存在 %d 条警告，运行 lint 子命令查看详情", warnings)
+	}
+}
+
 func applyWin32Overrides(pi *core.ProjectInterface, classRegex, windowRegex string) {
 	if pi == nil {
 		return
@@ -119,12 +143,21 @@ func applyWin32Overrides(pi *core.ProjectInterface, classRegex, windowRegex stri
 }
 
 // GetCapabilities 获取设备能力
-func (w *Wrapper) GetCapabilities() (*client.CapabilitiesPayload, error) {
+// lang 为空时使用配置的 maaend.language；all 为 true 时附带 interface.json 声明的所有语言
+func (w *Wrapper) GetCapabilities(lang string, all bool) (*client.CapabilitiesPayload, error) {
 	if !w.initialized {
 		return nil, fmt.Errorf("MaaFramework 未初始化")
 	}
 
-	builder := core.NewCapabilitiesBuilder(w.pi, "zh_cn")
+	if lang == "" {
+		if cfg := config.Get(); cfg != nil {
+			lang = cfg.MaaEnd.Language
+		}
+	}
+	builder := core.NewCapabilitiesBuilder(w.project(), lang)
+	if all {
+		return builder.BuildAll(), nil
+	}
 	return builder.Build(), nil
 }
 
@@ -138,7 +171,7 @@ func (w *Wrapper) ConnectController(name string) error {
 	}
 
 	// 获取控制器配置
-	ctrlConfig := w.pi.GetController(name)
+	ctrlConfig := w.project().GetController(name)
 	if ctrlConfig == nil {
 		return fmt.Errorf("控制器不存在: %s", name)
 	}
@@ -187,6 +220,15 @@ func (w *Wrapper) ConnectController(name string) error {
 	return nil
 }
 
+// ExplainOptions 解析任务选项并返回每一步的追踪记录（不执行任务）
+func (w *Wrapper) ExplainOptions(task string, options map[string]interface{}) (*client.OptionExplanation, error) {
+	pi := w.project()
+	if pi == nil {
+		return nil, fmt.Errorf("MaaFramework 未初始化")
+	}
+	return core.NewOptionResolver(pi).ExplainTaskOptions(task, options)
+}
+
 // ScreenshotTargetLongSide MaaEnd 资源基于 1280x720 设计，长边 1280
 // MaaFramework 会自动将截图缩放到此分辨率，保证 ROI 坐标正确匹配
 const ScreenshotTargetLongSide int32 = 1280
@@ -290,46 +332,16 @@ func (w *Wrapper) createAdbController(_ *core.ControllerConfig) (*maafw.Controll
 	return ctrl, nil
 }
 
-// LoadResource 加载资源
+// LoadResource 加载资源（资源文件变化后重新加载）
 func (w *Wrapper) LoadResource(name string) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	if w.currentResource == name && w.resource != nil {
+	if w.currentResource == name && w.resource != nil && !w.resourceStale {
 		return nil // 已加载
 	}
-
-	log.Printf("[Maa] 加载资源: %s", name)
-
-	// 获取资源路径
-	paths := w.pi.GetResourcePaths(name)
-	if len(paths) == 0 {
-		return fmt.Errorf("资源不存在: %s", name)
-	}
-
-	// 释放旧资源
-	if w.resource != nil {
-		w.resource.Destroy()
-		w.resource = nil
-	}
-
-	// 创建资源
-	res := maafw.NewResource()
-	if res == nil {
-		return fmt.Errorf("创建资源失败")
-	}
-
-	// 加载每个路径
-	for _, path := range paths {
-		log.Printf("[Maa] 加载资源路径: %s", path)
-		res.PostBundle(path).Wait()
-	}
-
-	w.resource = res
-	w.currentResource = name
-
-	log.Printf("[Maa] 资源加载完成: %s", name)
-	return nil
+	_, err := w.buildResource(name)
+	return err
 }
 
 // RunTask 执行任务
@@ -340,7 +352,17 @@ func (w *Wrapper) RunTask(job *client.Job, statusCh chan<- client.TaskStatusPayl
 		return fmt.Errorf("MaaFramework 未初始化")
 	}
 	w.stopRequested = false
+	w.running = true
 	w.mu.Unlock()
+	defer w.finishJob()
+
+	// 任务执行期间不会替换项目接口
+	pi := w.project()
+
+	// 校验选项（严格模式下有问题时不执行任何任务）
+	if err := w.checkJobOptions(pi, job, logCh); err != nil {
+		return err
+	}
 
 	// 连接控制器
 	if err := w.ConnectController(job.Controller); err != nil {
@@ -376,14 +398,14 @@ func (w *Wrapper) RunTask(job *client.Job, statusCh chan<- client.TaskStatusPayl
 	})
 
 	// 启动 Agent（如果配置了）
-	if w.pi.GetAgentExec() != "" {
+	if pi.GetAgentExec() != "" {
 		if err := w.startAgent(); err != nil {
 			log.Printf("[Maa] 启动 Agent 失败: %v (继续执行)", err)
 		}
 	}
 
 	// 创建选项解析器
-	resolver := core.NewOptionResolver(w.pi)
+	resolver := core.NewOptionResolver(pi)
 
 	// 执行每个任务
 	total := len(job.Tasks)
@@ -393,7 +415,7 @@ func (w *Wrapper) RunTask(job *client.Job, statusCh chan<- client.TaskStatusPayl
 		}
 
 		// 获取任务配置
-		taskConfig := w.pi.GetTask(taskItem.Name)
+		taskConfig := pi.GetTask(taskItem.Name)
 		if taskConfig == nil {
 			log.Printf("[Maa] 任务不存在: %s", taskItem.Name)
 			continue
@@ -430,6 +452,41 @@ func (w *Wrapper) RunTask(job *client.Job, statusCh chan<- client.TaskStatusPayl
 	return nil
 }
 
+// checkJobOptions 校验所有任务的选项
+// 严格模式（maaend.strict_options，可被任务的 strict_options 覆盖）下返回 core.OptionError，否则逐条上报 warn 日志
+func (w *Wrapper) checkJobOptions(pi *core.ProjectInterface, job *client.Job, logCh chan<- client.TaskLogPayload) error {
+	strict := false
+	if cfg := config.Get(); cfg != nil {
+		strict = cfg.MaaEnd.StrictOptions
+	}
+	if job.StrictOptions != nil {
+		strict = *job.StrictOptions
+	}
+
+	resolver := core.NewOptionResolver(pi)
+	var issues []client.OptionIssue
+	for _, taskItem := range job.Tasks {
+		issues = append(issues, resolver.CheckTaskOptions(taskItem.Name, taskItem.Options)...)
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	if strict {
+		return &core.OptionError{Issues: issues}
+	}
+
+	for _, issue := range issues {
+		log.Printf("[Maa] 选项警告: %s", issue.Message)
+		safeSendLog(logCh, client.TaskLogPayload{
+			JobID:     job.JobID,
+			Level:     "warn",
+			Message:   issue.Message,
+			EventType: "option",
+		})
+	}
+	return nil
+}
+
 // StopTask 停止任务
 func (w *Wrapper) StopTask() error {
 	w.mu.Lock()
@@ -476,7 +533,8 @@ func (w *Wrapper) TakeScreenshot() ([]byte, int, int, error) {
 
 // startAgent 启动 Agent
 func (w *Wrapper) startAgent() error {
-	agentExec := w.pi.GetAgentExec()
+	pi := w.project()
+	agentExec := pi.GetAgentExec()
 	if agentExec == "" {
 		return nil
 	}
@@ -490,7 +548,7 @@ func (w *Wrapper) startAgent() error {
 		w.agentServer = NewAgentServer()
 	}
 
-	return w.agentServer.Start(agentExec, w.pi.Agent.ChildArgs)
+	return w.agentServer.Start(agentExec, pi.Agent.ChildArgs)
 }
 
 // Cleanup 清理资源
@@ -498,6 +556,15 @@ func (w *Wrapper) Cleanup() {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	if w.interfaceWatcher != nil {
+		w.interfaceWatcher.Close()
+		w.interfaceWatcher = nil
+	}
+	if w.resourceWatcher != nil {
+		w.resourceWatcher.Close()
+		w.resourceWatcher = nil
+	}
+
 	if w.agentServer != nil {
 		w.agentServer.Stop()
 		w.agentServer = nil
@@ -524,18 +591,16 @@ func (w *Wrapper) Cleanup() {
 
 // GetProjectInterface 获取项目接口
 func (w *Wrapper) GetProjectInterface() *core.ProjectInterface {
-	return w.pi
+	return w.project()
 }
 
 // GetVersion 获取 MaaEnd 版本
 func (w *Wrapper) GetVersion() string {
-	if w.pi == nil {
-		return "unknown"
-	}
-	if w.pi.Version == "" {
+	pi := w.project()
+	if pi == nil || pi.Version == "" {
 		return "unknown"
 	}
-	return w.pi.Version
+	return pi.Version
 }
 
 // matchWindow 使用正则表达式匹配窗口
@@ -8,12 +8,18 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
+	"maaend-client/audit"
 	"maaend-client/client"
 	"maaend-client/config"
+	"maaend-client/core"
+	"maaend-client/localapi"
 	"maaend-client/maa"
+	"maaend-client/policy"
 	"maaend-client/store"
 )
 
@@ -22,10 +28,17 @@ var (
 	maaEndPath = flag.String("maaend", "", "MaaEnd 安装路径")
 	serverURL  = flag.String("server", "", "服务器 WebSocket 地址")
 	bindCode   = flag.String("bind", "", "绑定码（首次绑定时使用）")
+	unbind     = flag.Bool("unbind", false, "解除当前设备绑定后重新绑定")
 	debugMode  = flag.Bool("debug", false, "调试模式")
 )
 
 func main() {
+	// 子命令模式
+	if code, ok := runSubcommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
+	flag.Usage = printUsage
 	flag.Parse()
 
 	if err := ensureAdmin(); err != nil {
@@ -54,7 +67,27 @@ func main() {
 		cfg.MaaEnd.Path = *maaEndPath
 	}
 	if *serverURL != "" {
-		cfg.Server.WsURL = *serverURL
+		cfg.Server.URLOverride = *serverURL
+	}
+
+	// 初始化本地存储（设备令牌加密保存，是令牌的唯一来源）
+	localStorage, err := store.Open("", store.Options{
+		KeySource:  cfg.Security.CredentialKey,
+		Passphrase: os.Getenv(config.CredentialPassphraseEnv),
+	})
+	if err != nil {
+		log.Fatalf("%v（切换 security.credential_key 时需提供原口令；如需重新绑定，可删除 device.json 后重试）", err)
+	}
+	migrateLegacyToken(cfg, localStorage)
+	if localStorage.HasCredentials() {
+		log.Printf("已加载保存的设备凭证")
+	}
+
+	// 旧版本绑定的设备没有指令签名密钥，required 策略下会拒绝所有指令（包括下发密钥的 rotate_token）
+	if cfg.Security.CommandSigning == client.SigningRequired && localStorage.HasCredentials() &&
+		localStorage.GetSigningSecret() == "" && !*unbind {
+		log.Fatalf("security.command_signing 为 required，但当前设备没有指令签名密钥（绑定于旧版本）。" +
+			"请使用 -unbind 重新绑定设备以获取密钥，或暂时改为 optional")
 	}
 
 	// 确保配置文件格式正确（修复被 viper 破坏的格式）
@@ -68,13 +101,6 @@ func main() {
 	}
 	log.Printf("MaaEnd 路径: %s", cfg.MaaEnd.Path)
 
-	// 初始化本地存储
-	localStorage := store.NewStore("")
-	if localStorage.HasCredentials() {
-		cfg.Device.Token = localStorage.GetDeviceToken()
-		log.Printf("已加载保存的设备凭证")
-	}
-
 	// 初始化 MaaFramework
 	maaWrapper := maa.NewWrapper(cfg.MaaEnd.Path)
 	if err := maaWrapper.Init(); err != nil {
@@ -83,32 +109,44 @@ func main() {
 	defer maaWrapper.Cleanup()
 
 	// 创建 WebSocket 客户端
+	wrapperAdapter := &MaaWrapperAdapter{wrapper: maaWrapper}
 	wsClient := client.NewClient(cfg)
-	wsClient.SetMaaWrapper(&MaaWrapperAdapter{wrapper: maaWrapper})
+	wsClient.SetMaaWrapper(wrapperAdapter)
+	wsClient.SetCredentialStore(localStorage)
+	wsClient.SetCapabilitiesCache(filepath.Join(filepath.Dir(localStorage.Path()), "capabilities.json"))
 
-	// 设置回调
-	wsClient.SetCallbacks(
-		func() {
-			log.Println("[Main] 已连接到服务器")
-		},
-		func() {
-			log.Println("[Main] 与服务器断开连接")
-		},
-		func(msg *client.Message) {
-			// 处理注册成功
-			if msg.Type == client.MsgTypeRegistered {
-				var payload client.RegisteredPayload
-				if err := msg.ParsePayload(&payload); err == nil {
-					// 保存到本地存储
-					localStorage.SaveCredentials(payload.DeviceID, payload.DeviceToken, cfg.Device.Name)
-				}
-			}
-			// 处理认证失败
-			if msg.Type == client.MsgTypeAuthFailed {
-				localStorage.ClearCredentials()
-			}
-		},
-	)
+	// interface.json 重新加载后重新上报设备能力
+	maaWrapper.SetReloadHandler(wsClient.RefreshCapabilities)
+	if cfg.MaaEnd.WatchFiles {
+		if err := maaWrapper.WatchInterface(); err != nil {
+			log.Printf("警告: 无法监视 interface.json: %v", err)
+		}
+		if err := maaWrapper.WatchResources(); err != nil {
+			log.Printf("警告: 无法监视资源目录: %v", err)
+		}
+	}
+
+	// 打开审计日志
+	if cfg.Logging.AuditFile != "" {
+		auditLog, err := audit.Open(config.ResolvePath(cfg.Logging.AuditFile),
+			int64(cfg.Logging.AuditMaxSizeMB)*1024*1024, cfg.Logging.AuditMaxBackups)
+		if err != nil {
+			log.Printf("警告: 无法打开审计日志: %v", err)
+		} else {
+			defer auditLog.Close()
+			wsClient.SetAuditLog(auditLog)
+			log.Printf("审计日志: %s", auditLog.Path())
+		}
+	}
+
+	// 加载本地指令权限策略
+	if cfg.Security.PolicyFile != "" {
+		cmdPolicy, err := loadPolicy(cfg.Security.PolicyFile, maaWrapper.GetProjectInterface())
+		if err != nil {
+			log.Fatalf("加载指令权限策略失败: %v", err)
+		}
+		wsClient.SetPolicy(cmdPolicy)
+	}
 
 	// 创建上下文
 	ctx, cancel := context.WithCancel(context.Background())
@@ -125,45 +163,79 @@ func main() {
 		wsClient.Stop()
 	}()
 
-	// 处理绑定码
-	if *bindCode != "" {
-		// 使用命令行参数绑定
+	// 启动本地控制 API
+	if cfg.LocalAPI.Enabled {
+		apiCfg := cfg.LocalAPI
+		if apiCfg.Token == "" {
+			// 未配置令牌时生成随机令牌，保存在 device.json 同目录
+			tokenPath := filepath.Join(filepath.Dir(localStorage.Path()), "local_api.token")
+			token, err := localapi.LoadOrCreateToken(tokenPath)
+			if err != nil {
+				log.Fatalf("生成本地控制 API 令牌失败: %v", err)
+			}
+			apiCfg.Token = token
+			log.Printf("[Main] 本地控制 API 令牌保存在 %s", tokenPath)
+		}
+		apiServer, err := localapi.NewServer(apiCfg, wsClient, wrapperAdapter)
+		if err != nil {
+			log.Fatalf("启动本地控制 API 失败: %v", err)
+		}
 		go func() {
-			// 等待连接成功
-			for !wsClient.IsConnected() {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
+			if err := apiServer.Run(ctx); err != nil {
+				log.Printf("[Main] 本地控制 API 已停止: %v", err)
 			}
-			wsClient.SendRegister(*bindCode)
 		}()
-	} else if !wsClient.HasToken() {
-		// 没有 token，需要绑定
-		fmt.Println("\n设备未绑定，请按以下步骤操作：")
-		fmt.Println("1. 在 Web 端获取绑定码")
-		fmt.Println("2. 输入绑定码后按回车")
-		fmt.Print("\n请输入绑定码: ")
+	}
 
+	// 设置回调
+	wsClient.SetCallbacks(
+		func() {
+			log.Println("[Main] 已连接到服务器")
+		},
+		func() {
+			log.Println("[Main] 与服务器断开连接")
+		},
+		func(msg *client.Message) {
+			// 服务器解绑设备后重新进入绑定流程
+			if msg.Type == client.MsgTypeUnbind {
+				go promptBindCode(ctx, wsClient)
+			}
+		},
+	)
+
+	// 处理绑定码
+	if *unbind && wsClient.HasToken() {
+		// 本地解绑后重新绑定
+		go func() {
+			if !waitAuthenticated(ctx, wsClient) {
+				return
+			}
+			if err := wsClient.RequestUnbind("用户在客户端解除绑定"); err != nil {
+				log.Printf("[Main] 解除绑定失败: %v", err)
+				return
+			}
+			log.Println("[Main] 设备已解除绑定")
+			promptBindCode(ctx, wsClient)
+		}()
+	} else if *bindCode != "" {
+		// 使用命令行参数绑定
 		go func() {
-			reader := bufio.NewReader(os.Stdin)
-			for {
-				code, err := reader.ReadString('\n')
-				if err != nil {
-					continue
-				}
-				code = strings.TrimSpace(code)
-				if code != "" && wsClient.IsConnected() {
-					wsClient.SendRegister(code)
-					break
-				}
+			if waitConnected(ctx, wsClient) {
+				wsClient.SendRegister(*bindCode)
 			}
 		}()
+	} else if !wsClient.HasToken() {
+		// 没有 token，需要绑定
+		go promptBindCode(ctx, wsClient)
 	}
 
 	// 运行客户端
-	log.Printf("连接服务器: %s", cfg.Server.WsURL)
+	endpoints := cfg.Server.Endpoints()
+	if len(endpoints) > 1 {
+		log.Printf("服务器地址 (%s): %s", cfg.Server.URLStrategy, strings.Join(endpoints, ", "))
+	} else {
+		log.Printf("连接服务器: %s", strings.Join(endpoints, ""))
+	}
 	if err := wsClient.Run(ctx); err != nil {
 		if err != context.Canceled {
 			log.Printf("客户端退出: %v", err)
@@ -173,14 +245,113 @@ func main() {
 	log.Println("MaaEnd Client 已退出")
 }
 
+// loadPolicy 加载本地指令权限策略，并检查其中的名称是否存在于项目中
+func loadPolicy(path string, pi *core.ProjectInterface) (*policy.Policy, error) {
+	path = config.ResolvePath(path)
+	p, err := policy.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("已加载指令权限策略: %s", path)
+
+	if pi != nil {
+		var tasks, controllers, resources []string
+		for _, t := range pi.Tasks {
+			tasks = append(tasks, t.Name)
+		}
+		for _, c := range pi.Controllers {
+			controllers = append(controllers, c.Name)
+		}
+		for _, r := range pi.Resources {
+			resources = append(resources, r.Name)
+		}
+		for _, w := range p.Validate(tasks, controllers, resources) {
+			log.Printf("警告: %s", w)
+		}
+	}
+
+	return p, nil
+}
+
+// migrateLegacyToken 将旧版本保存在配置文件中的令牌迁移到本地存储，并从配置文件中移除
+func migrateLegacyToken(cfg *config.Config, localStorage *store.Store) {
+	if cfg.Device.Token == "" {
+		return
+	}
+
+	imported, err := localStorage.ImportLegacyToken(cfg.Device.Token)
+	if err != nil {
+		log.Printf("警告: 迁移配置文件中的设备令牌失败: %v", err)
+		return
+	}
+	if imported {
+		log.Printf("已将配置文件中的设备令牌迁移到加密存储")
+	}
+
+	if err := config.ClearLegacyToken(); err != nil {
+		log.Printf("警告: 从配置文件移除设备令牌失败: %v", err)
+	}
+}
+
+// promptBindCode 提示用户输入绑定码并发送注册请求
+func promptBindCode(ctx context.Context, wsClient *client.Client) {
+	fmt.Println("\n设备未绑定，请按以下步骤操作：")
+	fmt.Println("1. 在 Web 端获取绑定码")
+	fmt.Println("2. 输入绑定码后按回车")
+	fmt.Print("\n请输入绑定码: ")
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		code, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		if !waitConnected(ctx, wsClient) {
+			return
+		}
+		wsClient.SendRegister(code)
+		return
+	}
+}
+
+// waitConnected 等待连接到服务器，上下文取消时返回 false
+func waitConnected(ctx context.Context, wsClient *client.Client) bool {
+	return waitUntil(ctx, wsClient.IsConnected)
+}
+
+// waitAuthenticated 等待认证完成，上下文取消时返回 false
+func waitAuthenticated(ctx context.Context, wsClient *client.Client) bool {
+	return waitUntil(ctx, func() bool {
+		return wsClient.IsConnected() && wsClient.GetDeviceID() != ""
+	})
+}
+
+// waitUntil 轮询等待条件成立
+func waitUntil(ctx context.Context, cond func() bool) bool {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for !cond() {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
 // MaaWrapperAdapter 适配器，实现 MaaWrapperInterface
 type MaaWrapperAdapter struct {
 	wrapper *maa.Wrapper
 }
 
 // GetCapabilities 获取设备能力
-func (a *MaaWrapperAdapter) GetCapabilities() (*client.CapabilitiesPayload, error) {
-	return a.wrapper.GetCapabilities()
+func (a *MaaWrapperAdapter) GetCapabilities(lang string, all bool) (*client.CapabilitiesPayload, error) {
+	return a.wrapper.GetCapabilities(lang, all)
 }
 
 // RunTask 执行任务
@@ -207,3 +378,18 @@ func (a *MaaWrapperAdapter) ClearEventChannels() {
 func (a *MaaWrapperAdapter) GetVersion() string {
 	return a.wrapper.GetVersion()
 }
+
+// ExplainOptions 解析任务选项并返回追踪记录
+func (a *MaaWrapperAdapter) ExplainOptions(task string, options map[string]interface{}) (*client.OptionExplanation, error) {
+	return a.wrapper.ExplainOptions(task, options)
+}
+
+// ReloadInterface 重新加载 interface.json
+func (a *MaaWrapperAdapter) ReloadInterface() (*client.InterfaceReloadResult, error) {
+	return a.wrapper.ReloadInterface()
+}
+
+// ReloadResource 重新加载资源
+func (a *MaaWrapperAdapter) ReloadResource(name string) (*client.ResourceReloadResult, error) {
+	return a.wrapper.ReloadResource(name)
+}
@@ -0,0 +1,214 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 拒绝错误码
+const (
+	RejectMessageNotAllowed    = "message_not_allowed"    // 消息类型不在允许列表中
+	RejectTaskNotAllowed       = "task_not_allowed"       // 任务不在允许列表中
+	RejectControllerNotAllowed = "controller_not_allowed" // 控制器不在允许列表中
+	RejectResourceNotAllowed   = "resource_not_allowed"   // 资源不在允许列表中
+	RejectOutsideTimeWindow    = "outside_time_window"    // 当前时间不在允许的时间段内
+)
+
+// Policy 本地指令权限策略
+//
+// 各允许列表未配置时不做限制；配置为空列表时全部拒绝。
+// 时间段只限制启动任务（run_task），停止任务和截图不受影响。
+type Policy struct {
+	Messages    []string     `yaml:"messages"`     // 允许的服务器指令类型
+	Tasks       []string     `yaml:"tasks"`        // 允许的任务名
+	Controllers []string     `yaml:"controllers"`  // 允许的控制器名
+	Resources   []string     `yaml:"resources"`    // 允许的资源名
+	TimeWindows []TimeWindow `yaml:"time_windows"` // 允许启动任务的时间段（任一匹配即可）
+}
+
+// TimeWindow 时间段
+type TimeWindow struct {
+	Days  []string `yaml:"days"`  // 星期几（mon, tue, ...），为空表示每天
+	Start string   `yaml:"start"` // 开始时间 HH:MM
+	End   string   `yaml:"end"`   // 结束时间 HH:MM，小于开始时间表示跨午夜
+
+	days       map[time.Weekday]bool
+	start, end int // 当天的分钟数
+}
+
+// Violation 策略拒绝
+type Violation struct {
+	Code    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return v.Code + ": " + v.Message
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Load 加载策略文件
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取策略文件失败: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse 解析策略
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("解析策略文件失败: %w", err)
+	}
+
+	for i := range p.TimeWindows {
+		if err := p.TimeWindows[i].compile(); err != nil {
+			return nil, fmt.Errorf("time_windows[%d]: %w", i, err)
+		}
+	}
+
+	return &p, nil
+}
+
+// CheckMessage 检查服务器指令类型是否允许
+func (p *Policy) CheckMessage(msgType string) *Violation {
+	if p == nil || p.Messages == nil || contains(p.Messages, msgType) {
+		return nil
+	}
+	return &Violation{Code: RejectMessageNotAllowed, Message: fmt.Sprintf("本地策略不允许指令 %s", msgType)}
+}
+
+// CheckRun 检查任务请求是否允许
+func (p *Policy) CheckRun(controller, resource string, tasks []string, now time.Time) *Violation {
+	if p == nil {
+		return nil
+	}
+
+	if p.Controllers != nil && !contains(p.Controllers, controller) {
+		return &Violation{Code: RejectControllerNotAllowed, Message: fmt.Sprintf("本地策略不允许控制器 %s", controller)}
+	}
+	if p.Resources != nil && !contains(p.Resources, resource) {
+		return &Violation{Code: RejectResourceNotAllowed, Message: fmt.Sprintf("本地策略不允许资源 %s", resource)}
+	}
+	if p.Tasks != nil {
+		for _, task := range tasks {
+			if !contains(p.Tasks, task) {
+				return &Violation{Code: RejectTaskNotAllowed, Message: fmt.Sprintf("本地策略不允许任务 %s", task)}
+			}
+		}
+	}
+	if len(p.TimeWindows) > 0 && !p.inTimeWindow(now) {
+		return &Violation{Code: RejectOutsideTimeWindow, Message: "当前时间不在本地策略允许的时间段内"}
+	}
+
+	return nil
+}
+
+// Validate 检查策略中的任务、控制器、资源名是否存在于项目中，返回警告信息
+func (p *Policy) Validate(tasks, controllers, resources []string) []string {
+	if p == nil {
+		return nil
+	}
+
+	var warnings []string
+	check := func(kind string, allowed, known []string) {
+		for _, name := range allowed {
+			if !contains(known, name) {
+				warnings = append(warnings, fmt.Sprintf("策略中的%s %q 不存在", kind, name))
+			}
+		}
+	}
+	check("任务", p.Tasks, tasks)
+	check("控制器", p.Controllers, controllers)
+	check("资源", p.Resources, resources)
+	return warnings
+}
+
+// inTimeWindow 检查是否在任一时间段内
+func (p *Policy) inTimeWindow(now time.Time) bool {
+	for i := range p.TimeWindows {
+		if p.TimeWindows[i].contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// compile 解析时间段配置
+func (w *TimeWindow) compile() error {
+	var err error
+	if w.start, err = parseClock(w.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	if w.end, err = parseClock(w.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+
+	if len(w.Days) > 0 {
+		w.days = make(map[time.Weekday]bool, len(w.Days))
+		for _, d := range w.Days {
+			day, ok := weekdays[strings.ToLower(strings.TrimSpace(d))]
+			if !ok {
+				return fmt.Errorf("无效的星期: %s", d)
+			}
+			w.days[day] = true
+		}
+	}
+	return nil
+}
+
+// contains 检查时间是否在时间段内
+func (w *TimeWindow) contains(now time.Time) bool {
+	minute := now.Hour()*60 + now.Minute()
+	day := now.Weekday()
+
+	if w.start <= w.end {
+		return w.allowsDay(day) && minute >= w.start && minute < w.end
+	}
+
+	// 跨午夜：午夜后的部分属于前一天的时间段
+	if minute >= w.start {
+		return w.allowsDay(day)
+	}
+	if minute < w.end {
+		return w.allowsDay((day + 6) % 7)
+	}
+	return false
+}
+
+// allowsDay 检查星期是否允许
+func (w *TimeWindow) allowsDay(day time.Weekday) bool {
+	return w.days == nil || w.days[day]
+}
+
+// parseClock 解析 HH:MM（允许 24:00 表示当天结束）
+func parseClock(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("时间格式应为 HH:MM: %q", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
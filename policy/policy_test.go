@@ -0,0 +1,80 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+const testPolicy = `
+messages: [run_task, stop_task]
+tasks: [DailyRewards]
+controllers: [Win32]
+time_windows:
+  - days: [fri]
+    start: "22:00"
+    end: "02:00"
+`
+
+func TestCheckMessage(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := p.CheckMessage("stop_task"); v != nil {
+		t.Fatalf("stop_task 应允许: %v", v)
+	}
+	if v := p.CheckMessage("request_screenshot"); v == nil || v.Code != RejectMessageNotAllowed {
+		t.Fatalf("request_screenshot 应拒绝: %v", v)
+	}
+
+	var unrestricted *Policy
+	if v := unrestricted.CheckMessage("unbind"); v != nil {
+		t.Fatalf("未配置策略时不应限制: %v", v)
+	}
+}
+
+func TestCheckRun(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	friday := time.Date(2026, 10, 16, 23, 0, 0, 0, time.Local)
+	saturdayNight := time.Date(2026, 10, 17, 1, 30, 0, 0, time.Local)
+	saturdayLate := time.Date(2026, 10, 17, 23, 0, 0, 0, time.Local)
+
+	cases := []struct {
+		name       string
+		controller string
+		resource   string
+		tasks      []string
+		now        time.Time
+		want       string
+	}{
+		{"允许", "Win32", "Official", []string{"DailyRewards"}, friday, ""},
+		{"跨午夜", "Win32", "Official", []string{"DailyRewards"}, saturdayNight, ""},
+		{"控制器", "ADB", "Official", []string{"DailyRewards"}, friday, RejectControllerNotAllowed},
+		{"任务", "Win32", "Official", []string{"DailyRewards", "Shop"}, friday, RejectTaskNotAllowed},
+		{"时间段", "Win32", "Official", []string{"DailyRewards"}, saturdayLate, RejectOutsideTimeWindow},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := p.CheckRun(tc.controller, tc.resource, tc.tasks, tc.now)
+			got := ""
+			if v != nil {
+				got = v.Code
+			}
+			if got != tc.want {
+				t.Fatalf("CheckRun() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalidWindow(t *testing.T) {
+	if _, err := Parse([]byte("time_windows:\n  - start: \"8am\"\n    end: \"10:00\"\n")); err == nil {
+		t.Fatal("无效时间应解析失败")
+	}
+}
@@ -0,0 +1,259 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// 密钥来源
+const (
+	KeySourceMachine    = "machine"    // 由本机机器标识派生
+	KeySourcePassphrase = "passphrase" // 由用户口令派生
+)
+
+// dataKeyName 数据密钥在密钥环中的条目名
+const dataKeyName = "device-credentials"
+
+// 密钥派生参数
+const (
+	kekInfo          = "maaend-client/credential-kek/v1"
+	pbkdf2Iterations = 600000
+	keySize          = 32
+)
+
+// wrappedKey 密钥环中保存的数据密钥（由 KEK 加密）
+type wrappedKey struct {
+	Source string `json:"source"`
+	Salt   string `json:"salt"`
+	Key    string `json:"key"`
+}
+
+// Cipher 凭证加密器
+//
+// 凭证使用随机生成的数据密钥进行 AES-GCM 加密，数据密钥再由 KEK 加密后保存在密钥环中。
+// KEK 由机器标识（HKDF）或用户口令（PBKDF2）派生，更换机器或口令后无法解密。
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher 从密钥环加载数据密钥，不存在时生成新的数据密钥
+func NewCipher(keyring Keyring, source, passphrase string) (*Cipher, error) {
+	secret, err := kekSecret(source, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var dataKey []byte
+	raw, err := keyring.Get(dataKeyName)
+	switch {
+	case err == nil:
+		dataKey, err = unwrapDataKey(raw, source, secret)
+		var mismatch *sourceMismatchError
+		if errors.As(err, &mismatch) {
+			dataKey, err = rekeyDataKey(keyring, raw, mismatch.wrapped, source, secret, passphrase)
+		}
+		if err != nil {
+			return nil, err
+		}
+	case errors.Is(err, ErrKeyNotFound):
+		dataKey = make([]byte, keySize)
+		if _, err := rand.Read(dataKey); err != nil {
+			return nil, err
+		}
+		raw, err := wrapDataKey(dataKey, source, secret)
+		if err != nil {
+			return nil, err
+		}
+		if err := keyring.Set(dataKeyName, raw); err != nil {
+			return nil, fmt.Errorf("保存数据密钥失败: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("读取数据密钥失败: %w", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt 加密字符串，返回 base64(nonce || 密文)
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	sealed, err := seal(c.aead, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的结果
+func (c *Cipher) Decrypt(encoded string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("密文格式错误: %w", err)
+	}
+	plaintext, err := open(c.aead, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// kekSecret 获取派生 KEK 的原始秘密
+func kekSecret(source, passphrase string) ([]byte, error) {
+	switch source {
+	case "", KeySourceMachine:
+		id, err := machineID()
+		if err != nil {
+			return nil, fmt.Errorf("读取机器标识失败: %w", err)
+		}
+		return []byte(id), nil
+	case KeySourcePassphrase:
+		if passphrase == "" {
+			return nil, errors.New("未提供凭证口令")
+		}
+		return []byte(passphrase), nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥来源: %s (可选: machine, passphrase)", source)
+	}
+}
+
+// deriveKEK 派生 KEK
+func deriveKEK(source string, secret, salt []byte) ([]byte, error) {
+	if source == KeySourcePassphrase {
+		return pbkdf2.Key(sha256.New, string(secret), salt, pbkdf2Iterations, keySize)
+	}
+	return hkdf.Key(sha256.New, secret, salt, kekInfo, keySize)
+}
+
+// wrapDataKey 使用 KEK 加密数据密钥
+func wrapDataKey(dataKey []byte, source string, secret []byte) ([]byte, error) {
+	if source == "" {
+		source = KeySourceMachine
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	kek, err := deriveKEK(source, secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := seal(aead, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(&wrappedKey{
+		Source: source,
+		Salt:   base64.StdEncoding.EncodeToString(salt),
+		Key:    base64.StdEncoding.EncodeToString(sealed),
+	}, "", "  ")
+}
+
+// sourceMismatchError 数据密钥的保护来源与当前配置不同
+type sourceMismatchError struct {
+	wrapped, configured string
+}
+
+func (e *sourceMismatchError) Error() string {
+	return fmt.Sprintf("数据密钥由 %s 保护，当前配置为 %s", e.wrapped, e.configured)
+}
+
+// rekeyDataKey 切换密钥来源：用原来源的秘密解密数据密钥，再由新来源重新加密后保存
+// 数据密钥本身不变，已加密的凭证无需重新加密；原来源为 passphrase 时使用同一个口令
+func rekeyDataKey(keyring Keyring, raw []byte, from, to string, toSecret []byte, passphrase string) ([]byte, error) {
+	fromSecret, err := kekSecret(from, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("数据密钥由 %s 保护，当前配置为 %s，无法获取原来源的秘密: %w", from, to, err)
+	}
+	dataKey, err := unwrapDataKey(raw, from, fromSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	rewrapped, err := wrapDataKey(dataKey, to, toSecret)
+	if err != nil {
+		return nil, err
+	}
+	if err := keyring.Set(dataKeyName, rewrapped); err != nil {
+		return nil, fmt.Errorf("保存数据密钥失败: %w", err)
+	}
+	return dataKey, nil
+}
+
+// unwrapDataKey 使用 KEK 解密数据密钥
+func unwrapDataKey(raw []byte, source string, secret []byte) ([]byte, error) {
+	var wk wrappedKey
+	if err := json.Unmarshal(raw, &wk); err != nil {
+		return nil, fmt.Errorf("数据密钥格式错误: %w", err)
+	}
+	if source == "" {
+		source = KeySourceMachine
+	}
+	if wk.Source != source {
+		return nil, &sourceMismatchError{wrapped: wk.Source, configured: source}
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(wk.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("数据密钥格式错误: %w", err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(wk.Key)
+	if err != nil {
+		return nil, fmt.Errorf("数据密钥格式错误: %w", err)
+	}
+
+	kek, err := deriveKEK(source, secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := open(aead, sealed)
+	if err != nil {
+		return nil, errors.New("无法解密数据密钥（口令错误或机器已变更）")
+	}
+	return dataKey, nil
+}
+
+// newAEAD 创建 AES-256-GCM
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal 加密，输出 nonce || 密文
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open 解密 seal 的输出
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("密文长度不足")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
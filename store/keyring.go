@@ -0,0 +1,92 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrKeyNotFound 密钥环中不存在该条目
+var ErrKeyNotFound = errors.New("密钥不存在")
+
+// Keyring 密钥环接口，用于保存加密凭证所需的数据密钥
+type Keyring interface {
+	Get(name string) ([]byte, error)
+	Set(name string, value []byte) error
+	Delete(name string) error
+}
+
+// FileKeyring 基于文件的密钥环（每个条目一个文件，仅当前用户可读写）
+type FileKeyring struct {
+	dir string
+}
+
+// NewFileKeyring 创建文件密钥环，dir 为空时使用用户配置目录
+func NewFileKeyring(dir string) (*FileKeyring, error) {
+	if dir == "" {
+		base, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(base, "maaend-client", "keyring")
+	}
+	return &FileKeyring{dir: dir}, nil
+}
+
+// Get 读取条目
+func (k *FileKeyring) Get(name string) ([]byte, error) {
+	path, err := k.entryPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// Set 写入条目
+func (k *FileKeyring) Set(name string, value []byte) error {
+	path, err := k.entryPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(k.dir, 0700); err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, value, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// Delete 删除条目
+func (k *FileKeyring) Delete(name string) error {
+	path, err := k.entryPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// entryPath 条目文件路径
+func (k *FileKeyring) entryPath(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", errors.New("无效的密钥名称: " + name)
+	}
+	return filepath.Join(k.dir, name+".key"), nil
+}
@@ -0,0 +1,25 @@
+//go:build darwin
+// +build darwin
+
+package store
+
+import (
+	"errors"
+	"os/exec"
+	"regexp"
+)
+
+var platformUUIDPattern = regexp.MustCompile(`"IOPlatformUUID" = "([^"]+)"`)
+
+// machineID 读取 IOPlatformUUID
+func machineID() (string, error) {
+	out, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+	if err != nil {
+		return "", err
+	}
+	m := platformUUIDPattern.FindSubmatch(out)
+	if m == nil {
+		return "", errors.New("未找到 IOPlatformUUID")
+	}
+	return string(m[1]), nil
+}
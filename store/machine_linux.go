@@ -0,0 +1,24 @@
+//go:build linux
+// +build linux
+
+package store
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// machineID 读取 systemd / dbus 的机器标识
+func machineID() (string, error) {
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id, nil
+		}
+	}
+	return "", errors.New("未找到 /etc/machine-id")
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !windows && !darwin
+// +build !linux,!windows,!darwin
+
+package store
+
+import "errors"
+
+// machineID 当前平台不支持读取机器标识，请使用口令保护凭证
+func machineID() (string, error) {
+	return "", errors.New("当前平台不支持机器标识")
+}
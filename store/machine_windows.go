@@ -0,0 +1,23 @@
+//go:build windows
+// +build windows
+
+package store
+
+import (
+	"golang.org/x/sys/windows/registry"
+)
+
+// machineID 读取注册表中的 MachineGuid
+func machineID() (string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Cryptography`, registry.QUERY_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return "", err
+	}
+	defer key.Close()
+
+	id, _, err := key.GetStringValue("MachineGuid")
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
@@ -2,26 +2,42 @@ package store
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
 )
 
-// Store 本地存储
+// Store 本地存储（设备令牌和指令签名密钥加密保存）
 type Store struct {
-	path string
-	data *StoreData
-	mu   sync.RWMutex
+	path   string
+	data   *StoreData
+	cipher *Cipher
+	mu     sync.RWMutex
 }
 
 // StoreData 存储数据
 type StoreData struct {
-	DeviceID    string            `json:"device_id"`
-	DeviceToken string            `json:"device_token"`
-	DeviceName  string            `json:"device_name"`
-	Extra       map[string]string `json:"extra,omitempty"`
+	DeviceID        string            `json:"device_id"`
+	DeviceToken     string            `json:"device_token,omitempty"`       // 内存中的明文令牌；旧版本文件中的明文令牌会在加载时迁移
+	EncryptedToken  string            `json:"device_token_enc,omitempty"`   // 加密后的令牌
+	SigningSecret   string            `json:"-"`                            // 内存中的明文指令签名密钥（只以密文落盘）
+	EncryptedSecret string            `json:"signing_secret_enc,omitempty"` // 加密后的指令签名密钥
+	DeviceName      string            `json:"device_name"`
+	Extra           map[string]string `json:"extra,omitempty"`
 }
 
+// Options 存储选项
+type Options struct {
+	Keyring    Keyring // 为空时使用用户配置目录下的文件密钥环
+	KeySource  string  // 密钥来源: machine, passphrase
+	Passphrase string  // KeySource 为 passphrase 时使用
+}
+
+// ErrNoCipher 未能初始化加密器，无法保存令牌
+var ErrNoCipher = errors.New("凭证加密不可用")
+
 var (
 	globalStore *Store
 	once        sync.Once
@@ -35,46 +51,109 @@ func GetStore() *Store {
 	return globalStore
 }
 
-// NewStore 创建存储实例
+// NewStore 创建存储实例（使用默认密钥环和机器标识保护令牌，忽略加载错误）
 func NewStore(path string) *Store {
+	s, err := Open(path, Options{})
+	if err != nil && s == nil {
+		s = newStore(path)
+	}
+	return s
+}
+
+// Open 打开存储，加载并解密已有数据；旧版本的明文令牌会被加密后写回
+func Open(path string, opts Options) (*Store, error) {
+	s := newStore(path)
+
+	keyring := opts.Keyring
+	if keyring == nil {
+		fk, err := NewFileKeyring("")
+		if err != nil {
+			return nil, fmt.Errorf("初始化密钥环失败: %w", err)
+		}
+		keyring = fk
+	}
+
+	c, err := NewCipher(keyring, opts.KeySource, opts.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("初始化凭证加密失败: %w", err)
+	}
+	s.cipher = c
+
+	migrated, err := s.load()
+	if err != nil {
+		return s, fmt.Errorf("加载本地凭证失败: %w", err)
+	}
+	if migrated {
+		if err := s.save(); err != nil {
+			return s, fmt.Errorf("迁移明文令牌失败: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// newStore 创建空的存储实例
+func newStore(path string) *Store {
 	if path == "" {
 		// 默认存储路径
 		exe, _ := os.Executable()
 		path = filepath.Join(filepath.Dir(exe), "device.json")
 	}
 
-	s := &Store{
+	return &Store{
 		path: path,
 		data: &StoreData{
 			Extra: make(map[string]string),
 		},
 	}
+}
 
-	// 加载已有数据
-	s.load()
-
-	return s
+// Path 获取存储文件路径
+func (s *Store) Path() string {
+	return s.path
 }
 
-// load 加载数据
-func (s *Store) load() error {
+// load 加载数据，返回是否读到了需要迁移的明文令牌
+func (s *Store) load() (bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	data, err := os.ReadFile(s.path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return false, nil
 		}
-		return err
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, s.data); err != nil {
+		return false, err
+	}
+
+	if s.data.EncryptedSecret != "" {
+		secret, err := s.cipher.Decrypt(s.data.EncryptedSecret)
+		if err != nil {
+			return false, fmt.Errorf("解密指令签名密钥失败: %w", err)
+		}
+		s.data.SigningSecret = secret
+	}
+
+	if s.data.EncryptedToken != "" {
+		token, err := s.cipher.Decrypt(s.data.EncryptedToken)
+		if err != nil {
+			s.data.DeviceToken = ""
+			return false, fmt.Errorf("解密设备令牌失败: %w", err)
+		}
+		s.data.DeviceToken = token
+		return false, nil
 	}
 
-	return json.Unmarshal(data, s.data)
+	return s.data.DeviceToken != "", nil
 }
 
-// save 保存数据
+// save 保存数据（先写临时文件再重命名，保证不会留下半写入的文件）
 func (s *Store) save() error {
-	data, err := json.MarshalIndent(s.data, "", "  ")
+	data, err := s.marshal()
 	if err != nil {
 		return err
 	}
@@ -85,7 +164,44 @@ func (s *Store) save() error {
 		return err
 	}
 
-	return os.WriteFile(s.path, data, 0644)
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// marshal 序列化落盘数据，令牌和指令签名密钥只以密文形式保存
+func (s *Store) marshal() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	onDisk := *s.data
+	var err error
+	if onDisk.EncryptedToken, err = s.encrypt(onDisk.DeviceToken); err != nil {
+		return nil, err
+	}
+	if onDisk.EncryptedSecret, err = s.encrypt(onDisk.SigningSecret); err != nil {
+		return nil, err
+	}
+	onDisk.DeviceToken = ""
+
+	return json.MarshalIndent(&onDisk, "", "  ")
+}
+
+// encrypt 加密待落盘的值，空值保持为空
+func (s *Store) encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	if s.cipher == nil {
+		return "", ErrNoCipher
+	}
+	return s.cipher.Encrypt(plaintext)
 }
 
 // GetDeviceID 获取设备ID
@@ -118,6 +234,24 @@ func (s *Store) SetDeviceToken(token string) error {
 	return s.save()
 }
 
+// GetSigningSecret 获取指令签名密钥
+func (s *Store) GetSigningSecret() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data.SigningSecret
+}
+
+// RotateCredentials 替换设备令牌，signingSecret 非空时同时替换指令签名密钥
+func (s *Store) RotateCredentials(token, signingSecret string) error {
+	s.mu.Lock()
+	s.data.DeviceToken = token
+	if signingSecret != "" {
+		s.data.SigningSecret = signingSecret
+	}
+	s.mu.Unlock()
+	return s.save()
+}
+
 // GetDeviceName 获取设备名称
 func (s *Store) GetDeviceName() string {
 	s.mu.RLock()
@@ -152,10 +286,11 @@ func (s *Store) SetExtra(key, value string) error {
 }
 
 // SaveCredentials 保存凭证
-func (s *Store) SaveCredentials(deviceID, deviceToken, deviceName string) error {
+func (s *Store) SaveCredentials(deviceID, deviceToken, signingSecret, deviceName string) error {
 	s.mu.Lock()
 	s.data.DeviceID = deviceID
 	s.data.DeviceToken = deviceToken
+	s.data.SigningSecret = signingSecret
 	s.data.DeviceName = deviceName
 	s.mu.Unlock()
 	return s.save()
@@ -166,10 +301,22 @@ func (s *Store) ClearCredentials() error {
 	s.mu.Lock()
 	s.data.DeviceID = ""
 	s.data.DeviceToken = ""
+	s.data.SigningSecret = ""
 	s.mu.Unlock()
 	return s.save()
 }
 
+// ImportLegacyToken 导入旧版本保存在配置文件中的令牌（已有凭证时忽略），返回是否导入
+func (s *Store) ImportLegacyToken(token string) (bool, error) {
+	if token == "" || s.HasCredentials() {
+		return false, nil
+	}
+	if err := s.SetDeviceToken(token); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // HasCredentials 检查是否有凭证
 func (s *Store) HasCredentials() bool {
 	s.mu.RLock()
@@ -0,0 +1,213 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func testOptions(t *testing.T, passphrase string) Options {
+	t.Helper()
+	keyring, err := NewFileKeyring(filepath.Join(t.TempDir(), "keyring"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Options{Keyring: keyring, KeySource: KeySourcePassphrase, Passphrase: passphrase}
+}
+
+func TestStoreEncryptsToken(t *testing.T) {
+	machine := testOptions(t, "")
+	machine.KeySource = KeySourceMachine
+
+	for name, opts := range map[string]Options{"machine": machine, "passphrase": testOptions(t, "secret")} {
+		t.Run(name, func(t *testing.T) {
+			if opts.KeySource == KeySourceMachine {
+				if _, err := machineID(); err != nil {
+					t.Skipf("无法读取机器标识: %v", err)
+				}
+			}
+			path := filepath.Join(t.TempDir(), "device.json")
+
+			s, err := Open(path, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveCredentials("dev-1", "token-abc", "signing-xyz", "pc"); err != nil {
+				t.Fatal(err)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(raw), "token-abc") || strings.Contains(string(raw), "signing-xyz") {
+				t.Fatalf("凭证以明文落盘: %s", raw)
+			}
+
+			reopened, err := Open(path, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := reopened.GetDeviceToken(); got != "token-abc" {
+				t.Fatalf("GetDeviceToken() = %q", got)
+			}
+			if got := reopened.GetSigningSecret(); got != "signing-xyz" {
+				t.Fatalf("GetSigningSecret() = %q", got)
+			}
+		})
+	}
+}
+
+func TestStoreSwitchesKeySource(t *testing.T) {
+	if _, err := machineID(); err != nil {
+		t.Skipf("无法读取机器标识: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "device.json")
+	passphrase := testOptions(t, "secret")
+	machine := passphrase
+	machine.KeySource = KeySourceMachine
+	machine.Passphrase = ""
+
+	s, err := Open(path, machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveCredentials("dev-1", "token-abc", "signing-xyz", "pc"); err != nil {
+		t.Fatal(err)
+	}
+
+	open := func(opts Options) *Store {
+		t.Helper()
+		s, err := Open(path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.GetDeviceToken() != "token-abc" || s.GetSigningSecret() != "signing-xyz" {
+			t.Fatalf("切换后凭证 = %q / %q", s.GetDeviceToken(), s.GetSigningSecret())
+		}
+		return s
+	}
+
+	// machine -> passphrase：数据密钥改由口令保护
+	open(passphrase)
+	raw, err := passphrase.Keyring.Get(dataKeyName)
+	if err != nil || !strings.Contains(string(raw), `"source": "passphrase"`) {
+		t.Fatalf("数据密钥未重新加密: %s, %v", raw, err)
+	}
+	wrong := passphrase
+	wrong.Passphrase = "other"
+	if _, err := Open(path, wrong); err == nil {
+		t.Fatal("重新加密后口令错误时应打开失败")
+	}
+
+	// passphrase -> machine：缺少原口令时无法切换
+	if _, err := Open(path, machine); err == nil {
+		t.Fatal("缺少原口令时应打开失败")
+	}
+	withPassphrase := machine
+	withPassphrase.Passphrase = "secret"
+	open(withPassphrase)
+	open(machine)
+}
+
+func TestStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device.json")
+	opts := testOptions(t, "secret")
+
+	s, err := Open(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveCredentials("dev-1", "token-abc", "", "pc"); err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := opts
+	wrong.Passphrase = "other"
+	if _, err := Open(path, wrong); err == nil {
+		t.Fatal("口令错误时应打开失败")
+	}
+}
+
+func TestStoreRejectsTamperedCiphertext(t *testing.T) {
+	for _, field := range []string{"device_token_enc", "signing_secret_enc"} {
+		t.Run(field, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "device.json")
+			opts := testOptions(t, "secret")
+
+			s, err := Open(path, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveCredentials("dev-1", "token-abc", "signing-xyz", "pc"); err != nil {
+				t.Fatal(err)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var data map[string]interface{}
+			if err := json.Unmarshal(raw, &data); err != nil {
+				t.Fatal(err)
+			}
+			sealed, err := base64.StdEncoding.DecodeString(data[field].(string))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 翻转密文最后一个字节（GCM 认证标签）
+			sealed[len(sealed)-1] ^= 0x01
+			data[field] = base64.StdEncoding.EncodeToString(sealed)
+			raw, _ = json.Marshal(data)
+			if err := os.WriteFile(path, raw, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := Open(path, opts); err == nil {
+				t.Fatal("密文被篡改时应打开失败")
+			}
+		})
+	}
+}
+
+func TestStoreMigratesPlaintextToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device.json")
+	legacy := `{"device_id":"dev-1","device_token":"legacy-token","device_name":"pc"}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(path, testOptions(t, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.GetDeviceToken(); got != "legacy-token" {
+		t.Fatalf("GetDeviceToken() = %q", got)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "legacy-token") || !strings.Contains(string(raw), "device_token_enc") {
+		t.Fatalf("明文令牌未迁移: %s", raw)
+	}
+
+	// 通过临时文件替换写回：不留下临时文件，权限收紧为仅当前用户可读写
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("临时文件未清理: %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("文件权限 = %o, want 600", perm)
+		}
+	}
+}